@@ -34,9 +34,22 @@ Usage:
   cf logtest
   cf submit [-f <file>] [<specifier>...]
   cf list [<specifier>...]
-  cf parse [<specifier>...]
+  cf parse [--statement] [<specifier>...]
   cf gen [<alias>]
-  cf test [<file>]
+  cf test [-t <time>] [-m <memory>] [-j <jobs>] [-e <eps>] [-i <interactor>]
+          [--validator <validator>] [--format <format>] [--watch] [--sandbox]
+          [--side-by-side] [--full] [<file>]
+  cf test --compare [-t <time>] [-m <memory>] [-e <eps>] [-n <count>]
+          [-g <generator>] [--sandbox]
+  cf stress [-n <count>] [-g <generator>] [-b <brute>] [--validator <validator>] [--sandbox] [<file>]
+  cf shrink [-b <brute>] [--hint <format>] [--sandbox] <input> [<file>]
+  cf bench [-r <runs>] [-t <time>] [-m <memory>] [-g <generator>] [-n <count>]
+           [--fraction <fraction>] [-f <file>] [<test>...]
+  cf history [-n <count>] [--full]
+  cf testcase (add | list) [--full]
+  cf testcase (rm | edit) [--full] <id>...
+  cf testcase gen-ans [-b <brute>] [--full] [<id>...]
+  cf import-tests [--full] <source>
   cf watch [all] [<specifier>...]
   cf open [<specifier>...]
   cf stand [<specifier>...]
@@ -55,6 +68,91 @@ Options:
   --version            Show version.
   -f <file>, --file <file>, <file>
                        Path to file. E.g. "a.cpp", "./temp/a.cpp"
+  -t <time>, --time-limit <time>
+                       Time limit of each sample in seconds. E.g. "2", "0.5".
+                       By default it's the time limit of current problem.
+                       "0" means no limit. The program is killed after
+                       running twice as long.
+  -m <memory>, --memory-limit <memory>
+                       Memory limit of each sample in MB. E.g. "256".
+                       By default it's "memory_limit" of the template or the
+                       memory limit of current problem. "0" means no limit.
+                       It's enforced by cgroup v2 or rlimit on Linux. Under
+                       rlimit, a run failing after an allocation beyond the
+                       limit is "Memory Limit Exceeded". Without cgroup v2
+                       the memory is sampled, so it's approximate, and "-"
+                       if the program exits before any sample.
+  -j <jobs>, --jobs <jobs>
+                       Number of samples tested at the same time [default: 1].
+  -e <eps>, --epsilon <eps>
+                       Compare real numbers in outputs, i.e. numbers with a
+                       "." or an exponent, with this error. E.g. "1e-6". The
+                       same value bounds both the absolute and the relative
+                       error, a number passes if either is within it. Other
+                       tokens, integers too, are compared exactly. By default
+                       it's "epsilon" in "problem.json" of current path, or
+                       "epsilon" of the template. Outputs are compared
+                       exactly if it's 0.
+  -i <interactor>, --interactor <interactor>
+                       Command of the interactor of an interactive problem.
+                       By default it's "interactor.cpp" (compiled by g++) or
+                       the executable "interactor" in current path.
+  --validator <validator>
+                       Command of a validator checking inputs. By default
+                       it's "validator.cpp" (compiled by g++) or the
+                       executable "validator" in current path.
+  --format <format>    Format of the report of "cf test", one of "text",
+                       "json" and "junit" [default: text]. Other messages
+                       are printed to standard error for "json" and "junit".
+                       With them the exit code is 1 too if there is no
+                       report, e.g. the code cannot be compiled.
+  --watch              Test again whenever the code or samples are changed.
+  --statement          Save the statement of the problem as "statement.md" in
+                       the folder of the problem, with its images in
+                       "images". Contest materials such as PDF statements
+                       are downloaded into the folder of the contest.
+  --side-by-side       Show the output and the answer side by side in the diff
+                       of a failed sample, instead of one after the other.
+  --compare            Run all codes in current path, e.g. a.py and a.cpp, on
+                       the samples and the inputs generated by the generator,
+                       and show their verdicts and times side by side.
+                       If their outputs differ, codes with the same output
+                       are marked with the same group, e.g. [A] and [B].
+  --sandbox            Run your code in a sandbox on Linux. It has no network
+                       and a read only file system, except the working
+                       folder of problems with file IO. Forbidden system
+                       calls such as creating processes are reported as
+                       "Security Violation". The time and memory include
+                       setting up the sandbox, which takes a few
+                       milliseconds and megabytes.
+  -n <count>, --count <count>
+                       Number of random tests. "cf stress" runs until a
+                       counterexample is found by default. "cf bench" and
+                       "cf test --compare" generate 1 and 10 tests by
+                       default. For "cf history", number of the latest runs
+                       shown, 20 by default.
+  -g <generator>, --generator <generator>
+                       Code of the generator. By default it's "gen.<suffix>"
+                       in current path. It's run as "<generator> <seed>".
+  -b <brute>, --brute <brute>
+                       Code of a trusted solution. By default it's
+                       "brute.<suffix>" in current path.
+  --hint <format>      Input format of "cf shrink". Lines are separated by
+                       ";". "x" is a token, "x[n]" is n tokens, and a line
+                       ending with "*n" is repeated n times.
+                       E.g. "n; a[n]", "n m; u v *m", "n q; a[n]; l r *q".
+  -r <runs>, --runs <runs>
+                       Number of runs on each input of "cf bench"
+                       [default: 10].
+  --fraction <fraction>
+                       "cf bench" warns when the median time is more than
+                       this fraction of the time limit [default: 0.5].
+  <test>               An input file or the ID of a test. E.g. "max.txt", "3".
+  <input>              Input file of a sample. E.g. "in3.txt", "3".
+  --full               Use the full tests in folder "tests" instead of the
+                       samples.
+  <source>             A zip or a folder of tests. E.g. a Polygon package.
+  <id>                 ID of a test, K of "inK.txt". E.g. "3".
   <specifier>          Any useful text. E.g.
                        "https://codeforces.com/contest/100",
                        "https://codeforces.com/contest/180/problem/A",
@@ -85,13 +183,79 @@ Examples:
                        Fetch all problems' samples of gym 100001 into
                        "{cf}/{gym}/100001".
   cf parse             Fetch samples of current problem into current path.
+  cf parse --statement 100
+                       Fetch samples and statements of contest 100. The
+                       statements are saved as "statement.md" with "$$$"
+                       LaTeX kept, so they can be read offline. Images and
+                       PDF statements are downloaded and linked locally.
   cf gen               Generate a code from default template.
   cf gen cpp           Generate a code from the template whose alias is "cpp"
                        into current path.
   cf test              Run the commands of a template in current path. Then
                        test all samples. If you want to add a new testcase,
                        create two files "inK.txt" and "ansK.txt" where K is
-                       a string with 0~9.
+                       a string with 0~9. The exit code is 1 if any sample
+                       failed.
+  cf test -t 1.5       Test all samples with a time limit of 1.5 seconds.
+  cf test -m 256 a.cpp Test a.cpp with a memory limit of 256 MB.
+  cf test -j 4         Test 4 samples at the same time. The results are still
+                       printed in the order of samples.
+  cf test -e 1e-6      Accept real numbers within an error of 1e-6.
+  cf test -i "python3 interactor.py"
+                       Test an interactive problem with an interactor.
+  cf test --format json
+                       Print the verdict, time, memory and diff of each
+                       sample as JSON. The input, output and answer of a
+                       failed sample are not truncated.
+  cf test --format junit > report.xml
+                       Save the report as JUnit XML.
+  cf test --compare    Compare all codes in current path. Inputs generated by
+                       "gen.<suffix>" on which they output differently are
+                       saved as new samples without answers.
+  cf test --sandbox a.cpp
+                       Test a.cpp pulled from someone else in the sandbox.
+  cf test --watch      Run "before_script" and test all samples again on
+                       every save of the code or the samples.
+  cf stress            Compile "gen", "brute" and your code in current path,
+                       then feed random inputs from "gen" into "brute" and
+                       your code until their outputs differ. The input is
+                       saved as a new sample "inK.txt" and "ansK.txt". "gen"
+                       and "brute" may run 10 times as long as your code,
+                       but at least 10 seconds.
+  cf stress -n 1000 -g gen.py -b slow.cpp a.cpp
+                       Stress test a.cpp with at most 1000 tests.
+  cf shrink in3.txt    Remove lines and tokens of "in3.txt" while "brute" and
+                       your code still output differently. The smallest
+                       input is saved as a new sample.
+  cf shrink --hint "n; a[n]" 3
+                       Remove elements of array a from "in3.txt" and fix n,
+                       then remove lines and tokens.
+  cf bench max.txt     Run your code 10 times on max.txt and report the
+                       min/median/p95/max time and the peak memory.
+  cf bench -r 5 -n 3 -g gen_max.cpp
+                       Run your code 5 times on each of 3 inputs generated
+                       by gen_max.cpp. Without inputs, "gen.<suffix>" or
+                       the samples in current path are used.
+  cf testcase add      Add a test "inK.txt" and "ansK.txt" by the editor in
+                       $EDITOR, or read the input from standard input, e.g.
+                       "python3 gen.py 1 | cf testcase add".
+  cf testcase list     List all tests and which inputs have no answers.
+  cf testcase rm 3 4   Remove test 3 and test 4.
+  cf testcase edit 3   Edit "in3.txt" and "ans3.txt" by the editor.
+  cf testcase gen-ans  Generate answers of the inputs without answers by
+                       running "brute" in current path.
+  cf testcase gen-ans -b slow.cpp 3
+                       Generate "ans3.txt" by running slow.cpp.
+  cf import-tests problem.zip
+                       Import tests of a Polygon package ("tests/01" and
+                       "tests/01.a") or a zip of "*.in" and "*.out" (or
+                       "*.ans") as new samples.
+  cf import-tests --full ./tests-dir
+                       Replace the full tests in folder "tests" with the
+                       tests in ./tests-dir. Test them by "cf test --full".
+  cf history           Show the verdicts of the latest runs of "cf test" in
+                       current path, and which change of the code made a
+                       passed sample fail.
   cf watch             Watch the first 10 submissions of current contest.
   cf watch all         Watch all submissions of current contest.
   cf open 1136a        Use default web browser to open the page of contest
@@ -121,6 +285,29 @@ File:
 
   "~/.cf/config"        Configuration file, including templates, etc.
   "~/.cf/session"       Session file, including cookies, handle, password, etc.
+  "~/.cf/cache"         Outputs of "before_script". It's skipped when the code,
+                        the headers it includes by #include "..." and the
+                        scripts are not changed. Headers in the include paths
+                        of the compiler are not checked.
+  "~/.cf/history"       Results of "cf test" and the tested codes. The latest
+                        200 runs of each problem are kept.
+
+  "statement.md"        Statement of a problem saved by "cf parse --statement".
+  "problem.json"        Settings of a problem in its folder, e.g. the epsilon
+                        of "cf test" {"epsilon": 1e-6}. "cf parse" writes the
+                        name, the limits, the IO files, the URL and the ID of
+                        the problem into it, which are used by other commands
+                        instead of guessing the problem from the path. It also
+                        keeps the inner tests of samples with multiple tests,
+                        so "cf test" tells which of them failed, if the page
+                        highlights the lines of the outputs too, or each of
+                        them outputs one line. They are dropped when the
+                        samples are changed by other commands. For problems
+                        reading "input_file" and writing "output_file", each
+                        sample runs in a temporary folder with these files.
+                        "cf stress", "cf shrink", "cf bench", "cf test
+                        --compare" and "cf testcase gen-ans" don't support
+                        these problems.
 
   "~" is the home directory of current user in your system.
 
@@ -152,7 +339,31 @@ Script in template:
   $%path%$   Path to source file (Excluding $%full%$, e.g. "/home/xalanq/")
   $%full%$   Full name of source file (e.g. "a.cpp")
   $%file%$   Name of source file (Excluding suffix, e.g. "a")
-  $%rand%$   Random string with 8 character (including "a-z" "0-9")`
+  $%rand%$   Random string with 8 character (including "a-z" "0-9")
+
+Checker:
+  If a problem accepts multiple answers, put a testlib style checker into the
+  folder of the problem, either as "checker.cpp" (compiled by g++) or as an
+  executable "checker". You could also set "checker" of a template to a
+  command. cf runs "<checker> <input> <output> <answer>" for each sample. Exit
+  code 0 means passed, 1 or 2 means failed. A checker running longer than 10
+  times the time limit, at least 10 seconds, is killed as "Checker Failed".
+
+Validator:
+  Put a testlib style validator into the folder of a problem, either as
+  "validator.cpp" or as an executable "validator". cf runs it with each input
+  as its standard input before judging in "cf test", "cf test --compare",
+  "cf stress" and "cf shrink". A non-zero exit code means the input is
+  invalid, which is reported as "Invalid Input" instead of a verdict of your
+  code. "cf test --compare" skips such a sample.
+
+Interactor:
+  For an interactive problem, put a testlib style interactor into the folder
+  of the problem, either as "interactor.cpp" or as an executable "interactor".
+  cf runs "<interactor> <input> <output> <answer>" for each sample and
+  connects its standard input/output with your program. Its exit code is the
+  verdict like a checker. The communication is printed when failed. Either
+  of them is killed if it doesn't exit within 5 seconds after the other.`
 	color.Output = ansi.NewAnsiStdout()
 
 	usage = strings.Replace(usage, `$%version%$`, version, 1)
@@ -165,8 +376,11 @@ Script in template:
 	client.Init(clnPath, config.Instance.Host, config.Instance.Proxy)
 
 	err := cmd.Eval(opts)
-	if err != nil {
+	if err != nil && err != cmd.ErrFailed {
 		color.Red(err.Error())
 	}
 	color.Unset()
+	if cmd.ExitFailure(err) {
+		os.Exit(1)
+	}
 }
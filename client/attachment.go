@@ -0,0 +1,131 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/NetWilliam/cf-tool/client/html"
+	"github.com/NetWilliam/cf-tool/pkg/logger"
+)
+
+// ImagesDir folder in the folder of a problem where images of the statement
+// are saved
+const ImagesDir = "images"
+
+// resolveURL of a link in a page of codeforces
+func (c *Client) resolveURL(ref string) (string, error) {
+	base, err := url.Parse(c.host + "/")
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(u).String(), nil
+}
+
+// localName a file name from the url which is not in used
+func localName(URL string, used map[string]bool) string {
+	name := "file"
+	if u, err := url.Parse(URL); err == nil {
+		if base, err := url.PathUnescape(path.Base(u.Path)); err == nil && base != "/" && base != "." {
+			name = strings.NewReplacer("/", "_", "\\", "_").Replace(base)
+		}
+	}
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; used[name]; i++ {
+		name = fmt.Sprintf("%v-%v%v", base, i, ext)
+	}
+	used[name] = true
+	return name
+}
+
+// download the file by the fetcher. A web page is an error since it's what
+// codeforces returns when the file is not available, e.g. for a login page
+func (c *Client) download(URL, filename string) error {
+	body, err := c.fetcher.GetRaw(URL)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(http.DetectContentType(body), "text/html") {
+		return fmt.Errorf("Cannot download %v: got a web page", URL)
+	}
+	logger.Debug("Downloaded %s to %s (%d bytes)", URL, filename, len(body))
+	return os.WriteFile(filename, body, 0644)
+}
+
+// downloadImages of the statement into the images folder of the problem. It
+// returns the local paths of the sources which are downloaded, relative to
+// the folder of the problem, and the first error
+func (c *Client) downloadImages(body []byte, problemPath string) (map[string]string, error) {
+	images := map[string]string{}
+	used := map[string]bool{}
+	var first error
+	for _, src := range html.StatementImages(body) {
+		if _, ok := images[src]; ok {
+			continue
+		}
+		URL, err := c.resolveURL(src)
+		if err == nil {
+			err = os.MkdirAll(filepath.Join(problemPath, ImagesDir), os.ModePerm)
+		}
+		name := localName(URL, used)
+		if err == nil {
+			err = c.download(URL, filepath.Join(problemPath, ImagesDir, name))
+		}
+		if err != nil {
+			logger.Error("Failed to download image %s: %v", src, err)
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		images[src] = path.Join(ImagesDir, name)
+	}
+	return images, first
+}
+
+// DownloadAttachments downloads the contest materials, e.g. PDF statements,
+// linked in the contest page into the folder of the contest. It returns the
+// paths of the downloaded files and the first error
+func (c *Client) DownloadAttachments(info Info, contestPath string) ([]string, error) {
+	if info.ProblemType == "acmsguru" {
+		return nil, nil
+	}
+	URL, err := info.ProblemSetURL(c.host)
+	if err != nil {
+		return nil, err
+	}
+	body, err := c.fetcher.Get(URL)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(contestPath, os.ModePerm); err != nil {
+		return nil, err
+	}
+	files := []string{}
+	used := map[string]bool{}
+	var first error
+	for _, link := range html.Attachments(body) {
+		URL, err := c.resolveURL(link)
+		if err == nil {
+			filename := filepath.Join(contestPath, localName(URL, used))
+			if err = c.download(URL, filename); err == nil {
+				files = append(files, filename)
+				continue
+			}
+		}
+		logger.Error("Failed to download attachment %s: %v", link, err)
+		if first == nil {
+			first = err
+		}
+	}
+	return files, first
+}
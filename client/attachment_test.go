@@ -0,0 +1,96 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeFetcher returns the files by their urls
+type fakeFetcher struct {
+	files map[string][]byte
+}
+
+func (f *fakeFetcher) Get(url string) ([]byte, error) {
+	return f.GetRaw(url)
+}
+
+func (f *fakeFetcher) GetJSON(url string) (map[string]interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (f *fakeFetcher) Post(url string, data url.Values) ([]byte, error) {
+	return nil, errors.New("not supported")
+}
+
+func (f *fakeFetcher) GetRaw(url string) ([]byte, error) {
+	if body, ok := f.files[url]; ok {
+		return body, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func pngBytes(t *testing.T) []byte {
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestDownloadImages(t *testing.T) {
+	picture := pngBytes(t)
+	c := &Client{host: "https://codeforces.com", fetcher: &fakeFetcher{map[string][]byte{
+		"https://espresso.codeforces.com/a.png": picture,
+		"https://codeforces.com/b.png":          []byte("<html><body>Login</body></html>"),
+	}}}
+	body := []byte(`<div class="problem-statement"><p>` +
+		`<img src="https://espresso.codeforces.com/a.png"/>` +
+		`<img src="https://espresso.codeforces.com/a.png"/>` +
+		`<img src="/b.png"/><img src="/c.png"/></p></div>`)
+	dir := t.TempDir()
+	images, err := c.downloadImages(body, dir)
+	if err == nil {
+		t.Error("downloadImages succeeded with a web page and a missing image")
+	}
+	want := map[string]string{"https://espresso.codeforces.com/a.png": "images/a.png"}
+	if len(images) != len(want) || images["https://espresso.codeforces.com/a.png"] != want["https://espresso.codeforces.com/a.png"] {
+		t.Errorf("downloadImages = %v, want %v", images, want)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ImagesDir, "a.png"))
+	if err != nil || !bytes.Equal(data, picture) {
+		t.Errorf("a.png = %v bytes (%v), want the %v bytes of the PNG", len(data), err, len(picture))
+	}
+	if _, err := os.Stat(filepath.Join(dir, ImagesDir, "b.png")); err == nil {
+		t.Error("the web page is saved as b.png")
+	}
+}
+
+func TestSaveStatementWithMissingImage(t *testing.T) {
+	c := &Client{host: "https://codeforces.com", fetcher: &fakeFetcher{map[string][]byte{
+		"https://codeforces.com/a.png": pngBytes(t),
+	}}}
+	body := []byte(`<div class="problem-statement"><div class="header"><div class="title">A. Sum</div></div>` +
+		`<div><p><img src="/a.png" alt="a"/><img src="/b.png" alt="b"/></p></div></div>`)
+	dir := t.TempDir()
+	imageErr, err := c.saveStatement(body, dir, nil)
+	if err != nil {
+		t.Fatalf("saveStatement failed: %v", err)
+	}
+	if imageErr == nil {
+		t.Error("saveStatement didn't report the missing image")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, StatementFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "![a](images/a.png)") || !strings.Contains(string(data), "![b](/b.png)") {
+		t.Errorf("statement = %q, want the local a.png and the remote b.png", data)
+	}
+}
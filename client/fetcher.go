@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 
@@ -22,6 +23,23 @@ type Fetcher interface {
 
 	// Post performs a POST request with form data
 	Post(url string, data url.Values) ([]byte, error)
+
+	// GetRaw performs a GET request and returns the bytes of the file as they
+	// are, e.g. an image or a PDF
+	GetRaw(url string) ([]byte, error)
+}
+
+// getRaw downloads the file by the HTTP client
+func getRaw(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%v returns %v", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
 }
 
 // HTTPFetcher implements Fetcher using standard HTTP client
@@ -63,6 +81,12 @@ func (f *HTTPFetcher) GetJSON(url string) (map[string]interface{}, error) {
 	return data, nil
 }
 
+// GetRaw performs a GET request by the HTTP client
+func (f *HTTPFetcher) GetRaw(url string) ([]byte, error) {
+	logger.Debug("HTTPFetcher GetRaw: %s", url)
+	return getRaw(f.client, url)
+}
+
 // Post performs a POST request using util.PostBody
 func (f *HTTPFetcher) Post(url string, data url.Values) ([]byte, error) {
 	logger.Debug("HTTPFetcher POST: %s", url)
@@ -78,15 +102,17 @@ func (f *HTTPFetcher) Post(url string, data url.Values) ([]byte, error) {
 	return body, nil
 }
 
-// BrowserFetcher implements Fetcher using MCP browser client
+// BrowserFetcher implements Fetcher using MCP browser client. Files are
+// downloaded by client, since the browser returns the rendered pages only
 type BrowserFetcher struct {
 	mcpClient *mcp.Client
+	client    *http.Client
 }
 
 // NewBrowserFetcher creates a new browser fetcher
-func NewBrowserFetcher(mcpClient *mcp.Client) *BrowserFetcher {
+func NewBrowserFetcher(mcpClient *mcp.Client, client *http.Client) *BrowserFetcher {
 	logger.Info("Initialized BrowserFetcher")
-	return &BrowserFetcher{mcpClient: mcpClient}
+	return &BrowserFetcher{mcpClient: mcpClient, client: client}
 }
 
 // Get performs a GET request using browser
@@ -107,6 +133,13 @@ func (f *BrowserFetcher) Get(url string) ([]byte, error) {
 	return []byte(content), nil
 }
 
+// GetRaw performs a GET request by the HTTP client with the cookies of the
+// session
+func (f *BrowserFetcher) GetRaw(url string) ([]byte, error) {
+	logger.Debug("BrowserFetcher GetRaw: %s", url)
+	return getRaw(f.client, url)
+}
+
 // GetJSON performs a GET request using browser and parses JSON
 func (f *BrowserFetcher) GetJSON(url string) (map[string]interface{}, error) {
 	logger.Debug("BrowserFetcher GetJSON: %s", url)
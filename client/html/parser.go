@@ -1,17 +1,24 @@
 package html
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"html"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/NetWilliam/cf-tool/pkg/logger"
+	"github.com/PuerkitoBio/goquery"
 )
 
 // ParseTestcases extracts test cases from problem HTML
 // Works with Codeforces problem page format
-func ParseTestcases(body []byte) (input, output [][]byte, err error) {
+// tests[i] is the index of the inner test of each line of input[i], see
+// testIndexes. It's nil if the input isn't grouped. answerTests[i] is the
+// same for output[i], whose lines are highlighted by some pages too
+func ParseTestcases(body []byte) (input, output [][]byte, tests, answerTests [][]int, err error) {
 	// Find all <pre> tags that are inside input or output divs
 	inputReg := regexp.MustCompile(`<div[^>]*class="input"[^>]*>[\s\S]*?<pre[^>]*>([\s\S]*?)</pre>`)
 	inputMatches := inputReg.FindAllSubmatch(body, -1)
@@ -20,7 +27,7 @@ func ParseTestcases(body []byte) (input, output [][]byte, err error) {
 	outputMatches := outputReg.FindAllSubmatch(body, -1)
 
 	if len(inputMatches) == 0 || len(outputMatches) == 0 {
-		return nil, nil, fmt.Errorf("Cannot parse sample with input %v and output %v", len(inputMatches), len(outputMatches))
+		return nil, nil, nil, nil, fmt.Errorf("Cannot parse sample with input %v and output %v", len(inputMatches), len(outputMatches))
 	}
 
 	count := len(inputMatches)
@@ -31,19 +38,50 @@ func ParseTestcases(body []byte) (input, output [][]byte, err error) {
 	for i := 0; i < count; i++ {
 		inputContent := extractTextContent(inputMatches[i][1])
 		input = append(input, []byte(inputContent+"\n"))
+		tests = append(tests, testIndexes(inputMatches[i][1], strings.Count(inputContent, "\n")+1))
 
 		outputContent := extractTextContent(outputMatches[i][1])
 		output = append(output, []byte(outputContent+"\n"))
+		answerTests = append(answerTests, testIndexes(outputMatches[i][1], strings.Count(outputContent, "\n")+1))
 	}
 
-	return input, output, nil
+	return input, output, tests, answerTests, nil
+}
+
+var testLineReg = regexp.MustCompile(`<div[^>]*class="[^"]*\btest-example-line-(\d+)\b`)
+
+// testIndexes of the lines of a sample input or output whose inner tests are
+// highlighted by "test-example-line-N" divs. N of the line of the number of
+// tests is 0, and N of the lines of the k-th test is k. It returns nil if the
+// lines are not grouped or don't match the lines of the input
+func testIndexes(htmlBytes []byte, lines int) []int {
+	matches := testLineReg.FindAllSubmatch(htmlBytes, -1)
+	if len(matches) != lines {
+		return nil
+	}
+	indexes := make([]int, lines)
+	grouped := false
+	for i, match := range matches {
+		index, err := strconv.Atoi(string(match[1]))
+		if err != nil {
+			return nil
+		}
+		indexes[i] = index
+		grouped = grouped || index > 0
+	}
+	if !grouped {
+		return nil
+	}
+	return indexes
 }
 
 // extractTextContent extracts text content from HTML, removing all tags
 // Preserves newlines while normalizing spaces and tabs
 // Handles both old format (<br> tags) and new format (<div> tags)
 func extractTextContent(htmlBytes []byte) string {
-	text := string(htmlBytes)
+	// A newline right after <pre> is not a part of the content in HTML, and
+	// it would shift the lines of the inner tests
+	text := strings.TrimPrefix(strings.TrimPrefix(string(htmlBytes), "\r"), "\n")
 
 	// STEP 1: Handle <div> tags (NEW format - recent contests)
 	// Replace closing </div> tags with newlines to preserve line breaks
@@ -93,8 +131,67 @@ func extractTextContent(htmlBytes []byte) string {
 	return text
 }
 
-// IsStandardIO checks if problem uses standard input/output
+// Header the name, the limits and the IO files in the header of a problem.
+// Texts are as they are shown, e.g. "2 seconds", "256 megabytes" and
+// "standard input"
+type Header struct {
+	Name        string
+	TimeLimit   string
+	MemoryLimit string
+	Input       string
+	Output      string
+	// Interactive whether the statement has an "Interaction" section
+	Interactive bool
+}
+
+var interactionReg = regexp.MustCompile(`(?i)class="section-title">\s*(Interaction|Протокол взаимодействия)\s*<`)
+
+// ParseHeader of the problem page
+func ParseHeader(body []byte) (*Header, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	header := doc.Find(".problem-statement .header").First()
+	if header.Length() == 0 {
+		return nil, errors.New("Cannot find the header of the problem")
+	}
+	property := func(class string) string {
+		s := header.Find(class).First()
+		title := s.Find(".property-title").Text()
+		return normalize(strings.TrimPrefix(s.Text(), title))
+	}
+	return &Header{
+		Name:        normalize(header.Find(".title").First().Text()),
+		TimeLimit:   property(".time-limit"),
+		MemoryLimit: property(".memory-limit"),
+		Input:       property(".input-file"),
+		Output:      property(".output-file"),
+		Interactive: interactionReg.Match(body),
+	}, nil
+}
+
+// standardIONames how the standard input and output are shown in English and
+// Russian
+var standardIONames = map[string]bool{
+	"standard input":    true,
+	"standard output":   true,
+	"стандартный ввод":  true,
+	"стандартный вывод": true,
+}
+
+// IsStandardFile whether the input or output in the header is the standard
+// input or output
+func IsStandardFile(name string) bool {
+	return name == "" || standardIONames[name]
+}
+
+// IsStandardIO checks if problem uses standard input/output. Problems without
+// a header, e.g. those with PDF statements, are considered standard
 func IsStandardIO(body []byte) bool {
-	standardIOMarker := []byte(`<div class="input-file"><div class="property-title">input</div>standard input</div><div class="output-file"><div class="property-title">output</div>standard output</div>`)
-	return !strings.Contains(string(body), string(standardIOMarker))
+	header, err := ParseHeader(body)
+	if err != nil {
+		return true
+	}
+	return IsStandardFile(header.Input) && IsStandardFile(header.Output)
 }
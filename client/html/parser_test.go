@@ -0,0 +1,95 @@
+package html
+
+import (
+	"reflect"
+	"testing"
+)
+
+// ioHeader a header of a problem with the input and output files
+func ioHeader(input, output string) string {
+	return `<div class="header"><div class="title">B. Files</div>` +
+		`<div class="time-limit"><div class="property-title">time limit per test</div>1 second</div>` +
+		`<div class="memory-limit"><div class="property-title">memory limit per test</div>512 megabytes</div>` +
+		`<div class="input-file"><div class="property-title">input</div>` + input + `</div>` +
+		`<div class="output-file"><div class="property-title">output</div>` + output + `</div></div>`
+}
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   []byte
+		header *Header
+	}{
+		{"standard", problemPage(problemHeader, ""),
+			&Header{"A. Sum", "2 seconds", "256 megabytes", "standard input", "standard output", false}},
+		{"files", problemPage(ioHeader("input.txt", "output.txt"), ""),
+			&Header{"B. Files", "1 second", "512 megabytes", "input.txt", "output.txt", false}},
+		{"interactive", problemPage(problemHeader, `<div class="section-title">Interaction</div>`),
+			&Header{"A. Sum", "2 seconds", "256 megabytes", "standard input", "standard output", true}},
+		{"russian interactive", problemPage(ioHeader("стандартный ввод", "стандартный вывод"),
+			`<div class="section-title">Протокол взаимодействия</div>`),
+			&Header{"B. Files", "1 second", "512 megabytes", "стандартный ввод", "стандартный вывод", true}},
+		{"no header", []byte(`<html><body><p>PDF</p></body></html>`), nil},
+	}
+	for _, test := range tests {
+		header, err := ParseHeader(test.body)
+		if test.header == nil {
+			if err == nil {
+				t.Errorf("%v: ParseHeader = %+v, want an error", test.name, header)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(header, test.header) {
+			t.Errorf("%v: ParseHeader = %+v, want %+v", test.name, header, test.header)
+		}
+	}
+}
+
+func TestIsStandardIO(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     []byte
+		standard bool
+	}{
+		{"standard", problemPage(problemHeader, ""), true},
+		{"russian", problemPage(ioHeader("стандартный ввод", "стандартный вывод"), ""), true},
+		{"files", problemPage(ioHeader("input.txt", "output.txt"), ""), false},
+		{"input file", problemPage(ioHeader("input.txt", "standard output"), ""), false},
+		{"output file", problemPage(ioHeader("standard input", "output.txt"), ""), false},
+		{"no header", []byte(`<html><body><p>PDF</p></body></html>`), true},
+	}
+	for _, test := range tests {
+		if standard := IsStandardIO(test.body); standard != test.standard {
+			t.Errorf("%v: IsStandardIO = %v, want %v", test.name, standard, test.standard)
+		}
+	}
+}
+
+func TestTestIndexes(t *testing.T) {
+	line := func(n int, text string) string {
+		return `<div class="test-example-line test-example-line-` + string(rune('0'+n)) + `">` + text + `</div>`
+	}
+	tests := []struct {
+		name    string
+		html    string
+		lines   int
+		indexes []int
+	}{
+		{"grouped", line(0, "2") + line(1, "1 2") + line(1, "3") + line(2, "4"), 4, []int{0, 1, 1, 2}},
+		{"odd classes", `<div class="test-example-line test-example-line-1 test-example-line-odd">1</div>` +
+			`<div class="test-example-line test-example-line-2 test-example-line-even">2</div>`, 2, []int{1, 2}},
+		{"not grouped", line(0, "1") + line(0, "2"), 2, nil},
+		{"plain", "1\n2\n", 2, nil},
+		{"more lines", line(0, "1") + line(1, "2"), 3, nil},
+		{"fewer lines", line(0, "1") + line(1, "2") + line(1, "3"), 2, nil},
+	}
+	for _, test := range tests {
+		if indexes := testIndexes([]byte(test.html), test.lines); !reflect.DeepEqual(indexes, test.indexes) {
+			t.Errorf("%v: testIndexes = %v, want %v", test.name, indexes, test.indexes)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package html
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// blockTags elements which are rendered as separate blocks in Markdown
+var blockTags = map[string]bool{
+	"p": true, "div": true, "center": true, "blockquote": true,
+	"ul": true, "ol": true, "pre": true, "table": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+var spaceReg = regexp.MustCompile(`[ \t\r\n]+`)
+
+// StatementImages the sources of the images in the statement of the problem
+// page
+func StatementImages(body []byte) []string {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	images := []string{}
+	doc.Find(".problem-statement img").Each(func(_ int, s *goquery.Selection) {
+		if src, ok := s.Attr("src"); ok && src != "" {
+			images = append(images, src)
+		}
+	})
+	return images
+}
+
+// attachmentReg links of the contest materials, e.g. PDF statements
+var attachmentReg = regexp.MustCompile(`(?i)/attachments/download/|\.pdf$`)
+
+// materialsReg captions of the sidebar block with the contest materials
+var materialsReg = regexp.MustCompile(`(?i)materials|материалы`)
+
+// Attachments the links of the contest materials in the contest page. Only
+// the materials block of the sidebar is looked at, other PDF links on the
+// page, e.g. in blogs, are not attachments
+func Attachments(body []byte) []string {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	links := []string{}
+	seen := map[string]bool{}
+	doc.Find(".sidebox").Each(func(_ int, box *goquery.Selection) {
+		if !materialsReg.MatchString(box.Find(".caption").First().Text()) {
+			return
+		}
+		box.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+			href, _ := s.Attr("href")
+			if attachmentReg.MatchString(href) && !seen[href] {
+				seen[href] = true
+				links = append(links, href)
+			}
+		})
+	})
+	return links
+}
+
+// ParseStatement converts the statement of the problem page to Markdown. It
+// covers the title, the limits, the legend, the input and output
+// specifications, the examples and the notes. "$$$" LaTeX is kept as it is.
+// Sources of images are replaced by their local copies in images
+func ParseStatement(body []byte, images map[string]string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	statement := doc.Find(".problem-statement").First()
+	if statement.Length() == 0 {
+		return "", errors.New("Cannot find the statement")
+	}
+
+	statement.Find("img").Each(func(_ int, s *goquery.Selection) {
+		if src, ok := s.Attr("src"); ok && images[src] != "" {
+			s.SetAttr("src", images[src])
+		}
+	})
+
+	blocks := []string{}
+	statement.Children().Each(func(_ int, s *goquery.Selection) {
+		switch {
+		case s.HasClass("header"):
+			blocks = append(blocks, header(s)...)
+		case s.HasClass("sample-tests"):
+			blocks = append(blocks, examples(s)...)
+		default:
+			if title := s.ChildrenFiltered(".section-title"); title.Length() > 0 {
+				blocks = append(blocks, "## "+normalize(title.Text()))
+			}
+			blocks = append(blocks, markdownBlocks(s)...)
+		}
+	})
+	return strings.Join(blocks, "\n\n") + "\n", nil
+}
+
+// header the title and the limits
+func header(s *goquery.Selection) []string {
+	blocks := []string{"# " + normalize(s.Find(".title").First().Text())}
+	limits := []string{}
+	for _, class := range []string{".time-limit", ".memory-limit", ".input-file", ".output-file"} {
+		limit := s.Find(class).First()
+		if limit.Length() == 0 {
+			continue
+		}
+		name := normalize(limit.Find(".property-title").Text())
+		value := normalize(strings.TrimPrefix(limit.Text(), limit.Find(".property-title").Text()))
+		limits = append(limits, fmt.Sprintf("- %v: %v", name, value))
+	}
+	if len(limits) > 0 {
+		blocks = append(blocks, strings.Join(limits, "\n"))
+	}
+	return blocks
+}
+
+// examples the samples as code blocks
+func examples(s *goquery.Selection) []string {
+	blocks := []string{"## " + normalize(s.ChildrenFiltered(".section-title").Text())}
+	s.Find(".input, .output").Each(func(_ int, io *goquery.Selection) {
+		blocks = append(blocks, "### "+normalize(io.Find(".title").First().Text()))
+		blocks = append(blocks, codeBlock(io.Find("pre").First()))
+	})
+	return blocks
+}
+
+// normalize collapses the white spaces of the text
+func normalize(text string) string {
+	return strings.TrimSpace(spaceReg.ReplaceAllString(text, " "))
+}
+
+// normalizeParagraph collapses the white spaces of each line of an inline
+// paragraph
+func normalizeParagraph(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(strings.Join(strings.Fields(line), " "))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// preText the text of a <pre>, where lines are in <div>s in new problems and
+// separated by <br> in old problems
+func preText(s *goquery.Selection) string {
+	var b strings.Builder
+	var walk func(s *goquery.Selection)
+	walk = func(s *goquery.Selection) {
+		s.Contents().Each(func(_ int, c *goquery.Selection) {
+			switch goquery.NodeName(c) {
+			case "#text":
+				b.WriteString(c.Text())
+			case "br":
+				b.WriteString("\n")
+			case "div":
+				walk(c)
+				b.WriteString("\n")
+			default:
+				walk(c)
+			}
+		})
+	}
+	walk(s)
+	return strings.Trim(b.String(), "\n")
+}
+
+func codeBlock(s *goquery.Selection) string {
+	return "```\n" + preText(s) + "\n```"
+}
+
+// markdownBlocks converts the children of s to Markdown blocks. Inline
+// children between block children are joined into paragraphs
+func markdownBlocks(s *goquery.Selection) []string {
+	blocks := []string{}
+	var inline strings.Builder
+	flush := func() {
+		if text := normalizeParagraph(inline.String()); text != "" {
+			blocks = append(blocks, text)
+		}
+		inline.Reset()
+	}
+	s.Contents().Each(func(_ int, c *goquery.Selection) {
+		name := goquery.NodeName(c)
+		if !blockTags[name] {
+			inline.WriteString(markdownInline(c))
+			return
+		}
+		flush()
+		if c.HasClass("section-title") {
+			return
+		}
+		switch name {
+		case "pre":
+			blocks = append(blocks, codeBlock(c))
+		case "ul", "ol":
+			blocks = append(blocks, markdownList(c, name == "ol"))
+		case "table":
+			if table, err := goquery.OuterHtml(c); err == nil {
+				blocks = append(blocks, table)
+			}
+		case "blockquote":
+			for _, block := range markdownBlocks(c) {
+				blocks = append(blocks, "> "+strings.ReplaceAll(block, "\n", "\n> "))
+			}
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			blocks = append(blocks, "### "+normalize(c.Text()))
+		default:
+			blocks = append(blocks, markdownBlocks(c)...)
+		}
+	})
+	flush()
+	return blocks
+}
+
+// markdownList items are indented so that their paragraphs stay in the list
+func markdownList(s *goquery.Selection, ordered bool) string {
+	items := []string{}
+	s.ChildrenFiltered("li").Each(func(i int, li *goquery.Selection) {
+		marker := "- "
+		if ordered {
+			marker = fmt.Sprintf("%v. ", i+1)
+		}
+		lines := strings.Split(strings.Join(markdownBlocks(li), "\n\n"), "\n")
+		for j := 1; j < len(lines); j++ {
+			if lines[j] != "" {
+				lines[j] = strings.Repeat(" ", len(marker)) + lines[j]
+			}
+		}
+		items = append(items, marker+strings.Join(lines, "\n"))
+	})
+	return strings.Join(items, "\n")
+}
+
+// markdownInline converts an inline element. Texts are kept as they are so
+// that LaTeX isn't broken by escaping
+func markdownInline(s *goquery.Selection) string {
+	children := func() string {
+		var b strings.Builder
+		s.Contents().Each(func(_ int, c *goquery.Selection) {
+			b.WriteString(markdownInline(c))
+		})
+		return b.String()
+	}
+	// The marks must touch the text, so white spaces are moved out of them
+	wrap := func(mark string) string {
+		text := children()
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" {
+			return text
+		}
+		left := text[:len(text)-len(strings.TrimLeft(text, " \n"))]
+		right := text[len(strings.TrimRight(text, " \n")):]
+		return left + mark + trimmed + mark + right
+	}
+	switch goquery.NodeName(s) {
+	case "#text":
+		return spaceReg.ReplaceAllString(s.Text(), " ")
+	case "br":
+		return "\\\n"
+	case "b", "strong":
+		return wrap("**")
+	case "i", "em":
+		return wrap("*")
+	case "tt", "code":
+		return wrap("`")
+	case "span":
+		// Old problems style texts by classes
+		switch {
+		case s.HasClass("tex-font-style-bf"):
+			return wrap("**")
+		case s.HasClass("tex-font-style-it"):
+			return wrap("*")
+		case s.HasClass("tex-font-style-tt"):
+			return wrap("`")
+		}
+	case "sup", "sub":
+		return "<" + goquery.NodeName(s) + ">" + children() + "</" + goquery.NodeName(s) + ">"
+	case "a":
+		href, _ := s.Attr("href")
+		return fmt.Sprintf("[%v](%v)", strings.TrimSpace(children()), href)
+	case "img":
+		src, _ := s.Attr("src")
+		alt, _ := s.Attr("alt")
+		return fmt.Sprintf("![%v](%v)", alt, src)
+	case "#comment", "script", "style":
+		return ""
+	}
+	return children()
+}
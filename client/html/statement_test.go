@@ -0,0 +1,84 @@
+package html
+
+import "testing"
+
+// problemPage a problem page with the given header and sections in the
+// statement
+func problemPage(header, sections string) []byte {
+	return []byte(`<html><body><div class="problemindexholder"><div class="ttypography">` +
+		`<div class="problem-statement">` + header + sections + `</div></div></div></body></html>`)
+}
+
+const problemHeader = `<div class="header"><div class="title">A. Sum</div>` +
+	`<div class="time-limit"><div class="property-title">time limit per test</div>2 seconds</div>` +
+	`<div class="memory-limit"><div class="property-title">memory limit per test</div>256 megabytes</div>` +
+	`<div class="input-file"><div class="property-title">input</div>standard input</div>` +
+	`<div class="output-file"><div class="property-title">output</div>standard output</div></div>`
+
+func TestParseStatement(t *testing.T) {
+	tests := []struct {
+		name      string
+		sections  string
+		images    map[string]string
+		statement string
+	}{
+		{"legend", `<div><p>Given $$$n$$$ integers, find   their <b>sum</b>.</p><p>Second <i>paragraph</i>.</p></div>`, nil,
+			"# A. Sum\n\n" +
+				"- time limit per test: 2 seconds\n- memory limit per test: 256 megabytes\n- input: standard input\n- output: standard output\n\n" +
+				"Given $$$n$$$ integers, find their **sum**.\n\nSecond *paragraph*.\n"},
+		{"input specification", `<div class="input-specification"><div class="section-title">Input</div>` +
+			`<p>The first line contains <span class="tex-font-style-tt">n</span>.</p><ul><li>one</li><li>two</li></ul></div>`, nil,
+			"# A. Sum\n\n" +
+				"- time limit per test: 2 seconds\n- memory limit per test: 256 megabytes\n- input: standard input\n- output: standard output\n\n" +
+				"## Input\n\nThe first line contains `n`.\n\n- one\n- two\n"},
+		{"examples", `<div class="sample-tests"><div class="section-title">Examples</div><div class="sample-test">` +
+			`<div class="input"><div class="title">Input</div><pre><div class="test-example-line">1 2</div><div class="test-example-line">3</div></pre></div>` +
+			`<div class="output"><div class="title">Output</div><pre>3<br/>3</pre></div></div></div>`, nil,
+			"# A. Sum\n\n" +
+				"- time limit per test: 2 seconds\n- memory limit per test: 256 megabytes\n- input: standard input\n- output: standard output\n\n" +
+				"## Examples\n\n### Input\n\n```\n1 2\n3\n```\n\n### Output\n\n```\n3\n3\n```\n"},
+		{"image and note", `<div class="note"><div class="section-title">Note</div><p>See <img src="https://espresso.codeforces.com/a.png" alt="pic"/>.</p></div>`,
+			map[string]string{"https://espresso.codeforces.com/a.png": "images/a.png"},
+			"# A. Sum\n\n" +
+				"- time limit per test: 2 seconds\n- memory limit per test: 256 megabytes\n- input: standard input\n- output: standard output\n\n" +
+				"## Note\n\nSee ![pic](images/a.png).\n"},
+	}
+	for _, test := range tests {
+		statement, err := ParseStatement(problemPage(problemHeader, test.sections), test.images)
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+		if statement != test.statement {
+			t.Errorf("%v: ParseStatement = %q, want %q", test.name, statement, test.statement)
+		}
+	}
+}
+
+func TestParseStatementWithoutStatement(t *testing.T) {
+	if _, err := ParseStatement([]byte(`<html><body><p>PDF</p></body></html>`), nil); err == nil {
+		t.Error("ParseStatement of a page without a statement succeeded")
+	}
+}
+
+func TestAttachments(t *testing.T) {
+	body := []byte(`<html><body><div id="sidebar">` +
+		`<div class="roundbox sidebox"><div class="caption titled">&rarr; Contest materials</div><ul>` +
+		`<li><a href="/contest/1/attachments/download/7/statements.pdf">Statements (en)</a></li>` +
+		`<li><a href="/contest/1/attachments/download/8/tutorial">Tutorial</a></li>` +
+		`<li><a href="/contest/1/attachments/download/7/statements.pdf">Statements (en)</a></li>` +
+		`<li><a href="/blog/entry/2">Announcement</a></li></ul></div>` +
+		`<div class="roundbox sidebox"><div class="caption titled">&rarr; Recent actions</div>` +
+		`<a href="/files/rules.pdf">Rules</a></div></div>` +
+		`<div class="content"><a href="https://example.com/paper.pdf">Paper</a></div></body></html>`)
+	want := []string{"/contest/1/attachments/download/7/statements.pdf", "/contest/1/attachments/download/8/tutorial"}
+	links := Attachments(body)
+	if len(links) != len(want) {
+		t.Fatalf("Attachments = %v, want %v", links, want)
+	}
+	for i := range want {
+		if links[i] != want[i] {
+			t.Errorf("Attachments = %v, want %v", links, want)
+		}
+	}
+}
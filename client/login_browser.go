@@ -13,7 +13,7 @@ func (c *Client) SetMCPClient(mcpClient *mcp.Client) {
 	c.browserEnabled = true
 
 	// Initialize browser fetcher
-	c.fetcher = NewBrowserFetcher(mcpClient)
+	c.fetcher = NewBrowserFetcher(mcpClient, c.client)
 }
 
 // CloseBrowserClient closes the browser client
@@ -15,8 +15,13 @@ import (
 	"github.com/fatih/color"
 )
 
-// ParseProblem parse problem to path. mu can be nil
-func (c *Client) ParseProblem(URL, path string, mu *sync.Mutex) (samples int, standardIO bool, err error) {
+// StatementFile name of the file which keeps the statement of a problem
+const StatementFile = "statement.md"
+
+// ParseProblem parse problem to path and write the metadata of the problem
+// into problem.json. The statement is saved as Markdown if statement is true,
+// with links to the attachments of the contest. mu can be nil
+func (c *Client) ParseProblem(info Info, URL, path string, statement bool, attachments []string, mu *sync.Mutex) (samples int, standardIO bool, err error) {
 	logger.Info("Parsing problem: URL=%s, path=%s", URL, path)
 
 	body, err := c.fetcher.Get(URL)
@@ -27,7 +32,41 @@ func (c *Client) ParseProblem(URL, path string, mu *sync.Mutex) (samples int, st
 
 	logger.Debug("Fetched problem page: size=%d bytes", len(body))
 
-	input, output, err := html.ParseTestcases(body)
+	warn := func(format string, a ...interface{}) {
+		if mu != nil {
+			mu.Lock()
+			defer mu.Unlock()
+		}
+		color.Red(format, a...)
+	}
+	notice := func(format string, a ...interface{}) {
+		if mu != nil {
+			mu.Lock()
+			defer mu.Unlock()
+		}
+		color.Yellow(format, a...)
+	}
+
+	standardIO = html.IsStandardIO(body)
+
+	logger.Debug("Standard IO: %v", standardIO)
+
+	// The statement and the metadata are saved even if there is no sample,
+	// e.g. the problem only has a PDF statement
+	if statement {
+		imageErr, e := c.saveStatement(body, path, attachments)
+		if e != nil {
+			warn("Cannot save the statement: %v", e.Error())
+			logger.Error("Failed to save the statement of %s: %v", URL, e)
+		} else if imageErr != nil {
+			notice("The statement is saved without some images: %v", imageErr.Error())
+		}
+	}
+	input, output, tests, answerTests, err := html.ParseTestcases(body)
+	if e := saveProblem(body, info, URL, path, standardIO, tests, answerTests); e != nil {
+		warn("Cannot save %v: %v", ProblemFile, e.Error())
+		logger.Error("Failed to save %s of %s: %v", ProblemFile, URL, e)
+	}
 	if err != nil {
 		logger.Error("Failed to extract samples: %v", err)
 		return
@@ -35,10 +74,6 @@ func (c *Client) ParseProblem(URL, path string, mu *sync.Mutex) (samples int, st
 
 	logger.Info("Extracted %d sample(s)", len(input))
 
-	standardIO = html.IsStandardIO(body)
-
-	logger.Debug("Standard IO: %v", standardIO)
-
 	for i := 0; i < len(input); i++ {
 		fileIn := filepath.Join(path, fmt.Sprintf("in%v.txt", i+1))
 		fileOut := filepath.Join(path, fmt.Sprintf("ans%v.txt", i+1))
@@ -74,8 +109,72 @@ func (c *Client) ParseProblem(URL, path string, mu *sync.Mutex) (samples int, st
 	return len(input), standardIO, nil
 }
 
-// Parse parse
-func (c *Client) Parse(info Info) (problems []string, paths []string, err error) {
+// saveProblem updates problem.json in path with the metadata in the problem
+// page and the inner tests of the samples. Settings written by hand are kept
+func saveProblem(body []byte, info Info, URL, path string, standardIO bool, tests, answerTests [][]int) error {
+	problem, err := LoadProblem(path)
+	if err != nil {
+		return err
+	}
+	problem.URL = URL
+	problem.setInfo(info)
+	if header, err := html.ParseHeader(body); err == nil {
+		problem.setHeader(header, standardIO)
+	} else {
+		problem.StandardIO = standardIO
+	}
+	problem.SampleTests = sampleTests(tests)
+	problem.AnswerTests = sampleTests(answerTests)
+	return problem.Save(path)
+}
+
+// sampleTests the inner tests of each sample by its id, nil if no sample is
+// grouped
+func sampleTests(tests [][]int) map[string][]int {
+	var samples map[string][]int
+	for i, indexes := range tests {
+		if indexes == nil {
+			continue
+		}
+		if samples == nil {
+			samples = map[string][]int{}
+		}
+		samples[fmt.Sprint(i+1)] = indexes
+	}
+	return samples
+}
+
+// saveStatement converts the statement in the problem page to Markdown and
+// saves it to path with its images. Problems which only have PDF statements
+// get a statement with the links to the attachments. imageErr is the first
+// image which cannot be downloaded, whose link is kept as it is
+func (c *Client) saveStatement(body []byte, path string, attachments []string) (imageErr, err error) {
+	images, imageErr := c.downloadImages(body, path)
+	text, err := html.ParseStatement(body, images)
+	if err != nil {
+		if len(attachments) == 0 {
+			return nil, err
+		}
+		text = fmt.Sprintf("# %v\n", strings.ToUpper(filepath.Base(path)))
+	}
+	if len(attachments) > 0 {
+		text += "\n## Attachments\n\n"
+		for _, attachment := range attachments {
+			link := attachment
+			if rel, err := filepath.Rel(path, attachment); err == nil {
+				link = filepath.ToSlash(rel)
+			}
+			text += fmt.Sprintf("- [%v](%v)\n", filepath.Base(attachment), link)
+		}
+	}
+	if err = os.WriteFile(filepath.Join(path, StatementFile), []byte(text), 0644); err != nil {
+		return nil, err
+	}
+	return imageErr, nil
+}
+
+// Parse parse. The statements are saved if statement is true
+func (c *Client) Parse(info Info, statement bool) (problems []string, paths []string, err error) {
 	color.Cyan("Parse " + info.Hint())
 
 	logger.Debug("Parse info: ProblemID=%s, ProblemType=%s", info.ProblemID, info.ProblemType)
@@ -109,6 +208,18 @@ func (c *Client) Parse(info Info) (problems []string, paths []string, err error)
 	contestPath := info.Path()
 	logger.Info("The problem(s) will be saved to %v", contestPath)
 
+	var attachments []string
+	if statement {
+		attachments, err = c.DownloadAttachments(info, contestPath)
+		if err != nil {
+			color.Red("Cannot download attachments: %v", err.Error())
+		}
+		if len(attachments) > 0 {
+			color.Green("Downloaded %v attachments into %v", len(attachments), contestPath)
+		}
+		err = nil
+	}
+
 	wg := sync.WaitGroup{}
 	wg.Add(len(problems))
 	mu := sync.Mutex{}
@@ -127,14 +238,16 @@ func (c *Client) Parse(info Info) (problems []string, paths []string, err error)
 			}
 			URL := fmt.Sprintf(urlFormatter, problemID)
 
-			samples, standardIO, err := c.ParseProblem(URL, path, &mu)
+			problemInfo := info
+			problemInfo.ProblemID = problemID
+			samples, standardIO, err := c.ParseProblem(problemInfo, URL, path, statement, attachments, &mu)
 			if err != nil {
 				return
 			}
 
 			warns := ""
 			if !standardIO {
-				warns = color.YellowString("Non standard input output format.")
+				warns = color.YellowString("Non standard input output format. The files are saved in %v for `cf test`.", ProblemFile)
 			}
 			mu.Lock()
 			if err != nil {
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/NetWilliam/cf-tool/client/html"
+)
+
+// ProblemFile name of the file which keeps the settings of a problem
+const ProblemFile = "problem.json"
+
+// Problem settings of a problem, saved in the folder of the problem. The
+// metadata is written by `cf parse`, while the settings such as the epsilon
+// are written by hand
+type Problem struct {
+	Name        string `json:"name,omitempty"`
+	URL         string `json:"url,omitempty"`
+	ProblemType string `json:"problem_type,omitempty"`
+	ContestID   string `json:"contest_id,omitempty"`
+	GroupID     string `json:"group_id,omitempty"`
+	ProblemID   string `json:"problem_id,omitempty"`
+	// TimeLimit in seconds
+	TimeLimit float64 `json:"time_limit,omitempty"`
+	// MemoryLimit in megabytes
+	MemoryLimit uint64 `json:"memory_limit,omitempty"`
+	StandardIO  bool   `json:"standard_io,omitempty"`
+	// InputFile and OutputFile are the names of the files used instead of the
+	// standard input and output
+	InputFile   string `json:"input_file,omitempty"`
+	OutputFile  string `json:"output_file,omitempty"`
+	Interactive bool   `json:"interactive,omitempty"`
+	// SampleTests the index of the inner test of each line of the inputs of
+	// the samples with multiple tests, where 0 is the line of the number of
+	// tests. The key is the sample id
+	SampleTests map[string][]int `json:"sample_tests,omitempty"`
+	// AnswerTests the index of the inner test of each line of the answers,
+	// for the samples whose outputs are highlighted too
+	AnswerTests map[string][]int `json:"answer_tests,omitempty"`
+	// Epsilon the max absolute or relative error of real numbers in outputs
+	Epsilon float64 `json:"epsilon,omitempty"`
+}
+
+var (
+	timeLimitReg   = regexp.MustCompile(`([\d.]+)\s*(s|секунд)`)
+	memoryLimitReg = regexp.MustCompile(`(\d+)\s*(MB|megabyte|мегабайт)`)
+)
+
+// setHeader fills the metadata from the header of the problem page
+func (p *Problem) setHeader(header *html.Header, standardIO bool) {
+	p.Name = header.Name
+	if tmp := timeLimitReg.FindStringSubmatch(header.TimeLimit); tmp != nil {
+		p.TimeLimit, _ = strconv.ParseFloat(tmp[1], 64)
+	}
+	if tmp := memoryLimitReg.FindStringSubmatch(header.MemoryLimit); tmp != nil {
+		p.MemoryLimit, _ = strconv.ParseUint(tmp[1], 10, 64)
+	}
+	p.StandardIO = standardIO
+	p.InputFile, p.OutputFile = "", ""
+	if !html.IsStandardFile(header.Input) {
+		p.InputFile = header.Input
+	}
+	if !html.IsStandardFile(header.Output) {
+		p.OutputFile = header.Output
+	}
+	p.Interactive = header.Interactive
+}
+
+// setInfo fills the identifiers of the problem
+func (p *Problem) setInfo(info Info) {
+	p.ProblemType = info.ProblemType
+	p.ContestID = info.ContestID
+	p.GroupID = info.GroupID
+	p.ProblemID = info.ProblemID
+}
+
+// Info of the problem. ok is false if problem.json doesn't identify a problem
+func (p *Problem) Info() (info Info, ok bool) {
+	info = Info{
+		ProblemType: p.ProblemType,
+		ContestID:   p.ContestID,
+		GroupID:     p.GroupID,
+		ProblemID:   p.ProblemID,
+	}
+	return info, p.ProblemType != "" && p.ProblemID != ""
+}
+
+// LoadProblem load problem.json in path. If there is no such file, an empty
+// problem is returned
+func LoadProblem(path string) (*Problem, error) {
+	p := &Problem{}
+	b, err := os.ReadFile(filepath.Join(path, ProblemFile))
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Save problem.json to path
+func (p *Problem) Save(path string) error {
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(p); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(path, ProblemFile), data.Bytes(), 0644)
+}
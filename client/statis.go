@@ -3,7 +3,9 @@ package client
 import (
 	"errors"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/NetWilliam/cf-tool/pkg/logger"
 )
@@ -18,6 +20,34 @@ type StatisInfo struct {
 	State  string
 }
 
+// TimeLimit parse time limit from Limit, e.g. "2 s, 256 MB"
+func (s *StatisInfo) TimeLimit() (time.Duration, error) {
+	reg := regexp.MustCompile(`([\d.]+)\s*s\b`)
+	tmp := reg.FindStringSubmatch(s.Limit)
+	if tmp == nil {
+		return 0, errors.New("Cannot find time limit in " + s.Limit)
+	}
+	sec, err := strconv.ParseFloat(tmp[1], 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(sec * float64(time.Second)), nil
+}
+
+// MemoryLimit parse memory limit in bytes from Limit, e.g. "2 s, 256 MB"
+func (s *StatisInfo) MemoryLimit() (uint64, error) {
+	reg := regexp.MustCompile(`(\d+)\s*MB`)
+	tmp := reg.FindStringSubmatch(s.Limit)
+	if tmp == nil {
+		return 0, errors.New("Cannot find memory limit in " + s.Limit)
+	}
+	mb, err := strconv.ParseUint(tmp[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return mb * 1024 * 1024, nil
+}
+
 func findStatisBlock(body []byte) ([]byte, error) {
 	logger.Debug("Finding statis block in HTML (size=%d bytes)", len(body))
 
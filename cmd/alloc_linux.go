@@ -0,0 +1,120 @@
+//go:build linux && (amd64 || arm64)
+
+package cmd
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// allocWatchable whether the allocations of a program under RLIMIT_AS can be
+// watched, see installWatch
+const allocWatchable = true
+
+// seccompNotif and seccompNotifResp are struct seccomp_notif and struct
+// seccomp_notif_resp of linux/seccomp.h
+type seccompNotif struct {
+	ID    uint64
+	Pid   uint32
+	Flags uint32
+	Nr    int32
+	Arch  uint32
+	IP    uint64
+	Args  [6]uint64
+}
+
+type seccompNotifResp struct {
+	ID    uint64
+	Val   int64
+	Error int32
+	Flags uint32
+}
+
+// installWatch installs a seccomp filter which asks cf about every mmap,
+// mremap and brk of the current thread and what it executes. It returns the
+// listener, or -1 without the support of the kernel
+func installWatch() int {
+	const (
+		load  = unix.BPF_LD | unix.BPF_W | unix.BPF_ABS
+		jeq   = unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K
+		ret   = unix.BPF_RET | unix.BPF_K
+		nr    = 0
+		arch  = 4
+		allow = unix.SECCOMP_RET_ALLOW
+		ask   = unix.SECCOMP_RET_USER_NOTIF
+	)
+	filter := []unix.SockFilter{
+		{Code: load, K: arch},
+		{Code: jeq, K: auditArch, Jf: 4},
+		{Code: load, K: nr},
+		{Code: jeq, K: unix.SYS_MMAP, Jt: 3},
+		{Code: jeq, K: unix.SYS_MREMAP, Jt: 2},
+		{Code: jeq, K: unix.SYS_BRK, Jt: 1},
+		{Code: ret, K: allow},
+		{Code: ret, K: ask},
+	}
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return -1
+	}
+	listener, _, errno := unix.RawSyscall(unix.SYS_SECCOMP, unix.SECCOMP_SET_MODE_FILTER,
+		unix.SECCOMP_FILTER_FLAG_NEW_LISTENER, uintptr(unsafe.Pointer(&prog)))
+	if errno != 0 {
+		return -1
+	}
+	return int(listener)
+}
+
+// serveAllocations lets every call notified by listener go on, until the
+// program has exited or stop is closed. grow is called with the pid and the
+// bytes by which mmap or mremap grows the address space, brk with the pid
+// and the new program break. The listener is closed at the end
+func serveAllocations(listener, stop int, grow func(pid int, size uint64), brk func(pid int, addr uint64)) {
+	defer unix.Close(listener)
+	fds := []unix.PollFd{
+		{Fd: int32(listener), Events: unix.POLLIN},
+		{Fd: int32(stop), Events: unix.POLLIN},
+	}
+	for {
+		_, err := unix.Poll(fds, -1)
+		if err == unix.EINTR {
+			continue
+		}
+		// POLLHUP without POLLIN once the program has exited
+		if err != nil || fds[1].Revents != 0 || fds[0].Revents&unix.POLLIN == 0 {
+			return
+		}
+		var req seccompNotif
+		if err := seccompIoctl(listener, unix.SECCOMP_IOCTL_NOTIF_RECV, unsafe.Pointer(&req)); err != nil {
+			if err == unix.EINTR || err == unix.ENOENT {
+				continue
+			}
+			return
+		}
+		switch req.Nr {
+		case unix.SYS_MMAP:
+			grow(int(req.Pid), req.Args[1])
+		case unix.SYS_MREMAP:
+			if req.Args[2] > req.Args[1] {
+				grow(int(req.Pid), req.Args[2]-req.Args[1])
+			}
+		case unix.SYS_BRK:
+			// brk(0) only asks for the current break
+			if req.Args[0] != 0 {
+				brk(int(req.Pid), req.Args[0])
+			}
+		}
+		// ENOENT if the program was killed in the meantime
+		resp := seccompNotifResp{ID: req.ID, Flags: unix.SECCOMP_USER_NOTIF_FLAG_CONTINUE}
+		seccompIoctl(listener, unix.SECCOMP_IOCTL_NOTIF_SEND, unsafe.Pointer(&resp))
+	}
+}
+
+func seccompIoctl(fd int, req uint, arg unsafe.Pointer) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), uintptr(req), uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build linux && !(amd64 || arm64)
+
+package cmd
+
+// allocWatchable the seccomp filter is only built for amd64 and arm64
+const allocWatchable = false
+
+func installWatch() int {
+	return -1
+}
+
+func serveAllocations(listener, stop int, grow func(pid int, size uint64), brk func(pid int, addr uint64)) {
+}
@@ -13,31 +13,64 @@ import (
 
 // ParsedArgs parsed arguments
 type ParsedArgs struct {
-	Info      client.Info
-	File      string
-	Specifier []string `docopt:"<specifier>"`
-	Alias     string   `docopt:"<alias>"`
-	Accepted  bool     `docopt:"ac"`
-	All       bool     `docopt:"all"`
-	Handle    string   `docopt:"<handle>"`
-	Version   string   `docopt:"{version}"`
-	Config    bool     `docopt:"config"`
-	Submit    bool     `docopt:"submit"`
-	List      bool     `docopt:"list"`
-	Parse     bool     `docopt:"parse"`
-	Gen       bool     `docopt:"gen"`
-	Test      bool     `docopt:"test"`
-	Watch     bool     `docopt:"watch"`
-	Open      bool     `docopt:"open"`
-	Stand     bool     `docopt:"stand"`
-	Sid       bool     `docopt:"sid"`
-	Race      bool     `docopt:"race"`
-	Pull      bool     `docopt:"pull"`
-	Clone     bool     `docopt:"clone"`
-	Upgrade   bool     `docopt:"upgrade"`
-	McpPing   bool     `docopt:"mcp-ping"`
-	Mocka     bool     `docopt:"mocka"`
-	LogTest   bool     `docopt:"logtest"`
+	Info        client.Info
+	File        string
+	TimeLimit   string   `docopt:"--time-limit"`
+	MemoryLimit string   `docopt:"--memory-limit"`
+	Jobs        string   `docopt:"--jobs"`
+	Epsilon     string   `docopt:"--epsilon"`
+	Interactor  string   `docopt:"--interactor"`
+	Validator   string   `docopt:"--validator"`
+	Format      string   `docopt:"--format"`
+	WatchTest   bool     `docopt:"--watch"`
+	Sandbox     bool     `docopt:"--sandbox"`
+	Compare     bool     `docopt:"--compare"`
+	SideBySide  bool     `docopt:"--side-by-side"`
+	Statement   bool     `docopt:"--statement"`
+	Count       string   `docopt:"--count"`
+	Generator   string   `docopt:"--generator"`
+	Brute       string   `docopt:"--brute"`
+	Hint        string   `docopt:"--hint"`
+	Input       string   `docopt:"<input>"`
+	TestID      []string `docopt:"<id>"`
+	Source      string   `docopt:"<source>"`
+	Tests       []string `docopt:"<test>"`
+	Runs        string   `docopt:"--runs"`
+	Fraction    string   `docopt:"--fraction"`
+	Full        bool     `docopt:"--full"`
+	Specifier   []string `docopt:"<specifier>"`
+	Alias       string   `docopt:"<alias>"`
+	Accepted    bool     `docopt:"ac"`
+	All         bool     `docopt:"all"`
+	Handle      string   `docopt:"<handle>"`
+	Version     string   `docopt:"{version}"`
+	Config      bool     `docopt:"config"`
+	Submit      bool     `docopt:"submit"`
+	List        bool     `docopt:"list"`
+	Parse       bool     `docopt:"parse"`
+	Gen         bool     `docopt:"gen"`
+	Test        bool     `docopt:"test"`
+	Stress      bool     `docopt:"stress"`
+	Shrink      bool     `docopt:"shrink"`
+	Testcase    bool     `docopt:"testcase"`
+	Add         bool     `docopt:"add"`
+	Rm          bool     `docopt:"rm"`
+	Edit        bool     `docopt:"edit"`
+	GenAns      bool     `docopt:"gen-ans"`
+	ImportTests bool     `docopt:"import-tests"`
+	Bench       bool     `docopt:"bench"`
+	History     bool     `docopt:"history"`
+	Watch       bool     `docopt:"watch"`
+	Open        bool     `docopt:"open"`
+	Stand       bool     `docopt:"stand"`
+	Sid         bool     `docopt:"sid"`
+	Race        bool     `docopt:"race"`
+	Pull        bool     `docopt:"pull"`
+	Clone       bool     `docopt:"clone"`
+	Upgrade     bool     `docopt:"upgrade"`
+	McpPing     bool     `docopt:"mcp-ping"`
+	Mocka       bool     `docopt:"mocka"`
+	LogTest     bool     `docopt:"logtest"`
 }
 
 // Args global variable
@@ -92,6 +125,15 @@ func parseArgs(opts docopt.Opts) error {
 			info.SubmissionID = value
 		}
 	}
+	// problem.json written by `cf parse` tells which problem current folder is
+	// for, so the path doesn't have to follow the folder names in the config
+	if len(Args.Specifier) == 0 {
+		if problem, err := client.LoadProblem(path); err == nil {
+			if problemInfo, ok := problem.Info(); ok {
+				info = problemInfo
+			}
+		}
+	}
 	if info.ProblemType == "" {
 		parsed := parsePath(path)
 		if value, ok := parsed["problemType"]; ok {
@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/NetWilliam/cf-tool/config"
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/renderer"
+	"github.com/olekukonko/tablewriter/tw"
+)
+
+// benchInput an input to benchmark
+type benchInput struct {
+	name string
+	data []byte
+}
+
+// benchResult times of all runs on an input. Failure is the verdict if any
+// run didn't finish normally
+type benchResult struct {
+	times   []time.Duration
+	memory  uint64
+	failure string
+}
+
+// percentile by nearest rank of the sorted times
+func (b *benchResult) percentile(p float64) time.Duration {
+	if len(b.times) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(b.times)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return b.times[rank]
+}
+
+// readInput reads an input given by its path or by its test ID
+func readInput(name string) (*benchInput, error) {
+	data, err := os.ReadFile(name)
+	if err == nil {
+		return &benchInput{name, data}, nil
+	}
+	if data, e := os.ReadFile(inputPath(name)); e == nil {
+		return &benchInput{inputPath(name), data}, nil
+	}
+	return nil, err
+}
+
+// benchInputs are the given inputs. Without them, the inputs are generated
+// by the generator if there is one, otherwise they are all samples
+func benchInputs(templates []config.CodeTemplate) (inputs []*benchInput, err error) {
+	for _, name := range Args.Tests {
+		input, err := readInput(name)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, input)
+	}
+	if len(inputs) > 0 {
+		return
+	}
+
+	genFile := Args.Generator
+	if genFile == "" {
+		genFile = findCodeByName("gen", templates)
+	}
+	if genFile == "" {
+		for _, id := range getSampleID() {
+			input, err := readInput(id)
+			if err != nil {
+				return nil, err
+			}
+			inputs = append(inputs, input)
+		}
+		if len(inputs) == 0 {
+			return nil, errors.New("Cannot find any input. Specify inputs or create \"gen.<suffix>\"")
+		}
+		return
+	}
+
+	count := 1
+	if Args.Count != "" {
+		if count, err = strconv.Atoi(Args.Count); err != nil || count < 1 {
+			return nil, fmt.Errorf("Invalid count %v", Args.Count)
+		}
+	}
+	gen, command, err := prepareHelper(genFile, templates)
+	if err != nil {
+		return
+	}
+	defer cleanUp(gen, &err)
+	for i := 0; i < count; i++ {
+		seed := rand.Int63()
+		data, e, err := runHelper(command, []string{fmt.Sprint(seed)}, nil, judgeLimit{}, os.Stderr)
+		if err != nil {
+			return nil, err
+		}
+		if e.Err != nil {
+			return nil, fmt.Errorf("Generator failed with seed %v: %v", seed, e.Err.Error())
+		}
+		inputs = append(inputs, &benchInput{fmt.Sprintf("seed %v", seed), data})
+	}
+	return inputs, nil
+}
+
+// bench runs the command on the input for runs times
+func bench(command string, input *benchInput, runs int, limit judgeLimit) (*benchResult, error) {
+	result := &benchResult{}
+	for i := 0; i < runs; i++ {
+		_, e, err := runCode(command, nil, input.data, limit, io.Discard)
+		if err != nil {
+			return nil, err
+		}
+		if e.Memory > result.memory {
+			result.memory = e.Memory
+		}
+		if verdict := e.verdict(); verdict != "" {
+			result.failure = verdict
+			break
+		}
+		if e.Err != nil {
+			result.failure = fmt.Sprintf("%v (%v)", verdictRuntimeError, e.Err.Error())
+			break
+		}
+		result.times = append(result.times, e.CPUTime)
+	}
+	sort.Slice(result.times, func(i, j int) bool {
+		return result.times[i] < result.times[j]
+	})
+	return result, nil
+}
+
+// Bench command
+func Bench() (err error) {
+	cfg := config.Instance
+	if len(cfg.Template) == 0 {
+		return errors.New("You have to add at least one code template by `cf config`")
+	}
+	runs, err := strconv.Atoi(Args.Runs)
+	if err != nil || runs < 1 {
+		return fmt.Errorf("Invalid number of runs %v", Args.Runs)
+	}
+	fraction, err := strconv.ParseFloat(Args.Fraction, 64)
+	if err != nil || fraction <= 0 {
+		return fmt.Errorf("Invalid fraction %v", Args.Fraction)
+	}
+	filename, index, err := getOneSolution(Args.File, cfg.Template)
+	if err != nil {
+		return
+	}
+	conf, err := newJudgeConfig(cfg.Template[index])
+	if err != nil {
+		return
+	}
+	if conf.Interactor != "" {
+		return errors.New("cf bench doesn't support interactive problems")
+	}
+	if err = checkStandardIO("cf bench", conf.InputFile, conf.OutputFile); err != nil {
+		return
+	}
+	inputs, err := benchInputs(cfg.Template)
+	if err != nil {
+		return
+	}
+	scripts, command, err := prepareCode(filename, cfg.Template[index])
+	if err != nil {
+		return
+	}
+	defer cleanUp(scripts, &err)
+
+	results := []*benchResult{}
+	for _, input := range inputs {
+		fmt.Printf("Running %v for %v times\n", input.name, runs)
+		result, err := bench(command, input, runs, conf.Limit)
+		if err != nil {
+			return err
+		}
+		results = append(results, result)
+	}
+
+	table := tablewriter.NewTable(color.Output,
+		tablewriter.WithRenderer(renderer.NewBlueprint(tw.Rendition{
+			Symbols: tw.NewSymbols(tw.StyleASCII),
+		})),
+		tablewriter.WithHeaderAutoFormat(tw.Off),
+	)
+	table.Header("INPUT", "MIN", "MEDIAN", "P95", "MAX", "MEMORY")
+	seconds := func(d time.Duration) string {
+		return fmt.Sprintf("%.3fs", d.Seconds())
+	}
+	for i, result := range results {
+		memory := formatMemory(result.memory)
+		if len(result.times) == 0 {
+			table.Append(inputs[i].name, "-", "-", "-", "-", memory)
+			continue
+		}
+		table.Append(inputs[i].name, seconds(result.times[0]), seconds(result.percentile(0.5)),
+			seconds(result.percentile(0.95)), seconds(result.times[len(result.times)-1]), memory)
+	}
+	if err = table.Render(); err != nil {
+		return
+	}
+
+	for i, result := range results {
+		if result.failure != "" {
+			color.Red("%v on %v", result.failure, inputs[i].name)
+			continue
+		}
+		median := result.percentile(0.5)
+		if conf.Limit.Time > 0 && median.Seconds() > fraction*conf.Limit.Time.Seconds() {
+			color.Yellow("The median time on %v is %v, more than %v of the time limit %v",
+				inputs[i].name, seconds(median), fraction, seconds(conf.Limit.Time))
+		}
+	}
+	return
+}
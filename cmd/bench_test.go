@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	ms := func(values ...int) []time.Duration {
+		times := []time.Duration{}
+		for _, v := range values {
+			times = append(times, time.Duration(v)*time.Millisecond)
+		}
+		return times
+	}
+	tests := []struct {
+		times []time.Duration
+		p     float64
+		want  time.Duration
+	}{
+		{nil, 0.5, 0},
+		{ms(7), 0, 7 * time.Millisecond},
+		{ms(7), 0.95, 7 * time.Millisecond},
+		{ms(1, 2, 3, 4), 0, 1 * time.Millisecond},
+		{ms(1, 2, 3, 4), 0.5, 2 * time.Millisecond},
+		{ms(1, 2, 3, 4, 5), 0.5, 3 * time.Millisecond},
+		{ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10), 0.95, 10 * time.Millisecond},
+		{ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10), 0.9, 9 * time.Millisecond},
+		{ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10), 1, 10 * time.Millisecond},
+	}
+	for _, test := range tests {
+		b := &benchResult{times: test.times}
+		if got := b.percentile(test.p); got != test.want {
+			t.Errorf("percentile(%v) of %v = %v, want %v", test.p, test.times, got, test.want)
+		}
+	}
+}
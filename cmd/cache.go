@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+const (
+	// cachePath where the outputs of before_script are kept
+	cachePath = "~/.cf/cache"
+	// cacheEntries the number of compilations kept in the cache
+	cacheEntries = 32
+	// cacheExpiry entries unused for such a long time are removed
+	cacheExpiry = 7 * 24 * time.Hour
+)
+
+// cacheEntry what before_script produced for a source. Files maps the names of
+// the files in current folder, where the scripts run, to their hashes
+type cacheEntry struct {
+	Rand  string            `json:"rand"`
+	Files map[string]string `json:"files"`
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+var includeReg = regexp.MustCompile(`(?m)^\s*#\s*include\s*"([^"]+)"`)
+
+// localIncludes the files included by `#include "..."` in the source and in
+// them recursively, relative to the including file. Headers found in the
+// include paths of the compiler are not known
+func localIncludes(source string, content []byte, seen map[string]bool) []string {
+	files := []string{}
+	for _, tmp := range includeReg.FindAllSubmatch(content, -1) {
+		path := filepath.Join(filepath.Dir(source), string(tmp[1]))
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		files = append(files, path)
+		files = append(files, localIncludes(path, data, seen)...)
+	}
+	return files
+}
+
+// cacheDir of the compilation of the code, which is keyed on the source, the
+// local headers included by it, the name of the source and the scripts
+func (c *codeScripts) cacheDir() (string, error) {
+	root, err := homedir.Expand(cachePath)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(c.path, c.full)
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, s := range []string{c.full, c.template.BeforeScript, c.template.Script} {
+		fmt.Fprintf(h, "%v\x00", s)
+	}
+	h.Write(source)
+	for _, include := range localIncludes(path, source, map[string]bool{path: true}) {
+		hash, err := hashFile(include)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "\x00%v\x00%v", include, hash)
+	}
+	return filepath.Join(root, hex.EncodeToString(h.Sum(nil))), nil
+}
+
+// protectedReg files in the folder of a problem which are never cached, so a
+// cache hit can't overwrite them
+var protectedReg = regexp.MustCompile(`^((in|ans)\d+\.txt|problem\.json|statement\.md)$`)
+
+// cacheable whether the file in current folder may be an output of
+// before_script. created tells whether before_script created it. A changed
+// file must be named in script, e.g. "a.exe" of "./a.exe", so that the files
+// which are edited during the compilation are not taken
+func (c *codeScripts) cacheable(name string, created bool) bool {
+	if protectedReg.MatchString(name) || filepath.Clean(filepath.Join(c.path, c.full)) == name {
+		return false
+	}
+	if created {
+		return true
+	}
+	for _, arg := range splitCmd(c.filter(c.template.Script)) {
+		if filepath.Clean(arg) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// restoreCache copies the files of the entry back into current folder unless
+// they are the same. It returns false if there is no entry
+func (c *codeScripts) restoreCache(dir string) bool {
+	data, err := os.ReadFile(filepath.Join(dir, "entry.json"))
+	if err != nil {
+		return false
+	}
+	entry := cacheEntry{}
+	if err = json.Unmarshal(data, &entry); err != nil {
+		return false
+	}
+	for name := range entry.Files {
+		if !c.cacheable(name, true) {
+			return false
+		}
+	}
+	for name, hash := range entry.Files {
+		if h, err := hashFile(name); err == nil && h == hash {
+			continue
+		}
+		if err = copyFile(filepath.Join(dir, "files", name), name); err != nil {
+			return false
+		}
+	}
+	c.rand = entry.Rand
+	now := time.Now()
+	os.Chtimes(filepath.Join(dir, "entry.json"), now, now)
+	return true
+}
+
+// folderState the modification time and size of files in current folder,
+// where before_script runs
+func (c *codeScripts) folderState() map[string]fileState {
+	files := map[string]fileState{}
+	paths, err := os.ReadDir(".")
+	if err != nil {
+		return files
+	}
+	for _, path := range paths {
+		if info, err := path.Info(); err == nil && info.Mode().IsRegular() {
+			files[path.Name()] = fileState{info.ModTime(), info.Size()}
+		}
+	}
+	return files
+}
+
+// saveCache keeps the outputs of before_script, see cacheable. Nothing is
+// kept if there is no output
+func (c *codeScripts) saveCache(dir string, before map[string]fileState) error {
+	entry := cacheEntry{Rand: c.rand, Files: map[string]string{}}
+	for name, state := range c.folderState() {
+		old, existed := before[name]
+		if (existed && old == state) || !c.cacheable(name, !existed) {
+			continue
+		}
+		hash, err := hashFile(name)
+		if err != nil {
+			return err
+		}
+		entry.Files[name] = hash
+	}
+	if len(entry.Files) == 0 {
+		return nil
+	}
+	os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "files"), 0755); err != nil {
+		return err
+	}
+	for name := range entry.Files {
+		if err := copyFile(name, filepath.Join(dir, "files", name)); err != nil {
+			os.RemoveAll(dir)
+			return err
+		}
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(filepath.Join(dir, "entry.json"), data, 0644); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	pruneCache(filepath.Dir(dir))
+	return nil
+}
+
+// pruneCache removes expired entries and the least recently used entries
+// beyond cacheEntries
+func pruneCache(root string) {
+	paths, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	type used struct {
+		dir  string
+		time time.Time
+	}
+	entries := []used{}
+	for _, path := range paths {
+		dir := filepath.Join(root, path.Name())
+		info, err := os.Stat(filepath.Join(dir, "entry.json"))
+		if err != nil || time.Since(info.ModTime()) > cacheExpiry {
+			os.RemoveAll(dir)
+			continue
+		}
+		entries = append(entries, used{dir, info.ModTime()})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].time.After(entries[j].time)
+	})
+	for i := cacheEntries; i < len(entries); i++ {
+		os.RemoveAll(entries[i].dir)
+	}
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// programCompile command to compile the source of a judging program
+const programCompile = "g++ -std=c++17 -O2 -o %v %v"
+
+// findProgram returns the absolute path of a judging program called name in
+// current folder, e.g. "checker". If there is "<name>.cpp", it's compiled by
+// g++ when it's newer than the executable. Empty means there is no such
+// program
+func findProgram(name string) (string, error) {
+	source := name + ".cpp"
+	exe := name
+	if runtime.GOOS == "windows" {
+		exe += ".exe"
+	}
+	if src, err := os.Stat(source); err == nil {
+		if bin, err := os.Stat(exe); err != nil || bin.ModTime().Before(src.ModTime()) {
+			s := fmt.Sprintf(programCompile, exe, source)
+			// stdout may be the report of cf test, e.g. json
+			fmt.Fprintln(os.Stderr, s)
+			cmds := splitCmd(s)
+			cmd := exec.Command(cmds[0], cmds[1:]...)
+			cmd.Stdout = os.Stderr
+			cmd.Stderr = os.Stderr
+			if err := cmd.Run(); err != nil {
+				return "", fmt.Errorf("Cannot compile %v: %v", source, err.Error())
+			}
+		}
+	}
+	if _, err := os.Stat(exe); err == nil {
+		return filepath.Abs(exe)
+	}
+	return "", nil
+}
+
+// check the output with testlib's convention "checker <input> <output> <answer>".
+// Exit code 0 means accepted, 1 and 2 mean wrong answer and presentation
+// error. Anything else means the checker itself failed. It runs with the time
+// limit of a helper, see runHelper
+func check(checker, inPath, ansPath string, output []byte, limit judgeLimit) (ok bool, message string, err error) {
+	file, err := os.CreateTemp("", "cf-output-*.txt")
+	if err != nil {
+		return
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(output)
+	file.Close()
+	if err != nil {
+		return
+	}
+
+	var msg bytes.Buffer
+	stdout, e, err := runHelper(checker, []string{inPath, file.Name(), ansPath}, nil, limit, &msg)
+	message = strings.TrimSpace(msg.String() + string(stdout))
+	if err != nil {
+		return false, message, err
+	}
+	if e.Err == nil {
+		return true, message, nil
+	}
+	if exitErr, ok := e.Err.(*exec.ExitError); ok {
+		switch exitErr.ExitCode() {
+		case 1, 2:
+			return false, message, nil
+		}
+	}
+	return false, message, fmt.Errorf("%v %v", e.Err.Error(), message)
+}
+
+// validate the input with testlib's convention, the validator reads the
+// input from stdin and exits with 0 if it's valid. It runs with the time
+// limit of a helper, see runHelper
+func validate(validator string, input []byte, limit judgeLimit) (ok bool, message string, err error) {
+	var msg bytes.Buffer
+	stdout, e, err := runHelper(validator, nil, input, limit, &msg)
+	message = strings.TrimSpace(msg.String() + string(stdout))
+	if err != nil {
+		return false, message, err
+	}
+	if e.Err == nil {
+		return true, message, nil
+	}
+	if _, isExit := e.Err.(*exec.ExitError); isExit {
+		if message == "" {
+			message = e.Err.Error()
+		}
+		return false, message, nil
+	}
+	return false, message, e.Err
+}
+
+// validateFile validates the input file by the validator if there is one
+func validateFile(validator, path string, limit judgeLimit) (ok bool, message string, err error) {
+	if validator == "" {
+		return true, "", nil
+	}
+	input, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	return validate(validator, input, limit)
+}
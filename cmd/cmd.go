@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 
 	"github.com/docopt/docopt-go"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/NetWilliam/cf-tool/config"
 	"github.com/NetWilliam/cf-tool/util"
 	"github.com/fatih/color"
+	ansi "github.com/k0kubun/go-ansi"
 )
 
 // Eval opts
@@ -26,6 +28,10 @@ func Eval(opts docopt.Opts) error {
 		return Config()
 	} else if Args.Submit {
 		return Submit()
+	} else if Args.Testcase {
+		return Testcase()
+	} else if Args.ImportTests {
+		return ImportTests()
 	} else if Args.List {
 		return List()
 	} else if Args.Parse {
@@ -34,6 +40,14 @@ func Eval(opts docopt.Opts) error {
 		return Gen()
 	} else if Args.Test {
 		return Test()
+	} else if Args.Stress {
+		return Stress()
+	} else if Args.Shrink {
+		return Shrink()
+	} else if Args.Bench {
+		return Bench()
+	} else if Args.History {
+		return History()
 	} else if Args.Watch {
 		return Watch()
 	} else if Args.Open {
@@ -60,12 +74,30 @@ func Eval(opts docopt.Opts) error {
 	return nil
 }
 
-func getSampleID() (samples []string) {
-	path, err := os.Getwd()
-	if err != nil {
-		return
+// fullTestsDir where the full tests are imported, apart from the samples
+const fullTestsDir = "tests"
+
+// testDir the folder of the tests in use, which is current folder for the
+// samples
+func testDir() string {
+	if Args.Full {
+		return fullTestsDir
 	}
-	paths, err := os.ReadDir(path)
+	return "."
+}
+
+// inputPath of a test in testDir
+func inputPath(id string) string {
+	return filepath.Join(testDir(), fmt.Sprintf("in%v.txt", id))
+}
+
+// answerPath of a test in testDir
+func answerPath(id string) string {
+	return filepath.Join(testDir(), fmt.Sprintf("ans%v.txt", id))
+}
+
+func getSampleID() (samples []string) {
+	paths, err := os.ReadDir(testDir())
 	if err != nil {
 		return
 	}
@@ -75,8 +107,7 @@ func getSampleID() (samples []string) {
 		tmp := reg.FindSubmatch([]byte(name))
 		if tmp != nil {
 			idx := string(tmp[1])
-			ans := fmt.Sprintf("ans%v.txt", idx)
-			if _, err := os.Stat(ans); err == nil {
+			if _, err := os.Stat(answerPath(idx)); err == nil {
 				samples = append(samples, idx)
 			}
 		}
@@ -84,6 +115,82 @@ func getSampleID() (samples []string) {
 	return
 }
 
+// nextSampleID the smallest number which is larger than all samples' numbers
+func nextSampleID() string {
+	next := 1
+	paths, err := os.ReadDir(testDir())
+	if err != nil {
+		return fmt.Sprint(next)
+	}
+	reg := regexp.MustCompile(`^in(\d+).txt$`)
+	for _, path := range paths {
+		if tmp := reg.FindStringSubmatch(path.Name()); tmp != nil {
+			if id, err := strconv.Atoi(tmp[1]); err == nil && id >= next {
+				next = id + 1
+			}
+		}
+	}
+	return fmt.Sprint(next)
+}
+
+// dropSampleTests forgets the inner tests of the samples in problem.json,
+// since they don't match the samples any more once they are changed. The full
+// tests have no inner tests
+func dropSampleTests(ids ...string) error {
+	if Args.Full {
+		return nil
+	}
+	problem, err := client.LoadProblem(".")
+	if err != nil {
+		return err
+	}
+	changed := false
+	for _, id := range ids {
+		_, input := problem.SampleTests[id]
+		_, answer := problem.AnswerTests[id]
+		if input || answer {
+			delete(problem.SampleTests, id)
+			delete(problem.AnswerTests, id)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if len(problem.SampleTests) == 0 {
+		problem.SampleTests = nil
+	}
+	if len(problem.AnswerTests) == 0 {
+		problem.AnswerTests = nil
+	}
+	return problem.Save(".")
+}
+
+// judgingPrograms names of codes in the folder of a problem which are not
+// solutions. The commands which load them as helpers skip them when looking
+// for the solution
+var judgingPrograms = map[string]bool{
+	"checker":    true,
+	"interactor": true,
+	"gen":        true,
+	"brute":      true,
+	"validator":  true,
+}
+
+// findCodeByName finds "<name>.<suffix>" in current folder where suffix is
+// any suffix of the templates. Empty means there is no such code
+func findCodeByName(name string, templates []config.CodeTemplate) string {
+	for _, temp := range templates {
+		for _, suffix := range temp.Suffix {
+			filename := name + "." + suffix
+			if _, err := os.Stat(filename); err == nil {
+				return filename
+			}
+		}
+	}
+	return ""
+}
+
 // CodeList Name matches some template suffix, index are template array indexes
 type CodeList struct {
 	Name  string
@@ -91,6 +198,17 @@ type CodeList struct {
 }
 
 func getCode(filename string, templates []config.CodeTemplate) (codes []CodeList, err error) {
+	return findCodes(filename, templates, nil)
+}
+
+// getSolutionCode is getCode which skips the judgingPrograms
+func getSolutionCode(filename string, templates []config.CodeTemplate) (codes []CodeList, err error) {
+	return findCodes(filename, templates, judgingPrograms)
+}
+
+// findCodes matching the templates. If filename is empty, they are the files
+// in current folder except those named in skip
+func findCodes(filename string, templates []config.CodeTemplate, skip map[string]bool) (codes []CodeList, err error) {
 	mp := make(map[string][]int)
 	for i, temp := range templates {
 		suffixMap := map[string]bool{}
@@ -123,6 +241,9 @@ func getCode(filename string, templates []config.CodeTemplate) (codes []CodeList
 	for _, path := range paths {
 		name := path.Name()
 		ext := filepath.Ext(name)
+		if skip[name[:len(name)-len(ext)]] {
+			continue
+		}
 		if idx, ok := mp[ext]; ok {
 			codes = append(codes, CodeList{name, idx})
 		}
@@ -136,21 +257,38 @@ func getOneCode(filename string, templates []config.CodeTemplate) (name string,
 	if err != nil {
 		return
 	}
+	return chooseCode(codes, templates)
+}
+
+// getOneSolution is getOneCode which skips the judgingPrograms
+func getOneSolution(filename string, templates []config.CodeTemplate) (name string, index int, err error) {
+	codes, err := getSolutionCode(filename, templates)
+	if err != nil {
+		return
+	}
+	return chooseCode(codes, templates)
+}
+
+// chooseCode asks which code and which template to use if there are many.
+// The menu is printed to stderr, which keeps a report on stdout clean
+func chooseCode(codes []CodeList, templates []config.CodeTemplate) (name string, index int, err error) {
 	if len(codes) < 1 {
 		return "", 0, errors.New("Cannot find any code.\nMaybe you should add a new template by `cf config`")
 	}
+	stderr := ansi.NewAnsiStderr()
+	cyan := color.New(color.FgCyan)
 	if len(codes) > 1 {
-		color.Cyan("There are multiple files can be selected.")
+		cyan.Fprintln(stderr, "There are multiple files can be selected.")
 		for i, code := range codes {
-			fmt.Printf("%3v: %v\n", i, code.Name)
+			fmt.Fprintf(stderr, "%3v: %v\n", i, code.Name)
 		}
 		i := util.ChooseIndex(len(codes))
 		codes[0] = codes[i]
 	}
 	if len(codes[0].Index) > 1 {
-		color.Cyan("There are multiple languages match the file.")
+		cyan.Fprintln(stderr, "There are multiple languages match the file.")
 		for i, idx := range codes[0].Index {
-			fmt.Printf("%3v: %v\n", i, client.Langs[templates[idx].Lang])
+			fmt.Fprintf(stderr, "%3v: %v\n", i, client.Langs[templates[idx].Lang])
 		}
 		i := util.ChooseIndex(len(codes[0].Index))
 		codes[0].Index[0] = codes[0].Index[i]
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// decimalReg matches a decimal number. Hexadecimal numbers, inf and nan are
+// not
+var decimalReg = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+
+// realPair whether both tokens are decimal numbers and either is real, i.e.
+// has a "." or an exponent. Only they are compared with the epsilon
+func realPair(x, y []byte) bool {
+	return decimalReg.Match(x) && decimalReg.Match(y) &&
+		(bytes.ContainsAny(x, ".eE") || bytes.ContainsAny(y, ".eE"))
+}
+
+// floatEqual whether x is within the absolute or relative error eps of y.
+// The same eps bounds both, either being within it is enough
+func floatEqual(x, y, eps float64) bool {
+	diff := math.Abs(x - y)
+	return diff <= eps || diff <= eps*math.Abs(y)
+}
+
+// compareFloat compares the output with the answer token by token. Tokens
+// which are both numbers, one of them real, are equal if they are close
+// enough, see realPair. Others, e.g. two integers, must be the same. The
+// message tells where the first difference is, and at is the index of the
+// token there among all tokens, -1 if there is none
+func compareFloat(output, answer []byte, eps float64) (ok bool, message string, at int) {
+	out := bytes.Fields(output)
+	ans := bytes.Fields(answer)
+	for i := 0; i < len(out) && i < len(ans); i++ {
+		if bytes.Equal(out[i], ans[i]) {
+			continue
+		}
+		if realPair(out[i], ans[i]) {
+			x, errX := strconv.ParseFloat(string(out[i]), 64)
+			y, errY := strconv.ParseFloat(string(ans[i]), 64)
+			if errX == nil && errY == nil && floatEqual(x, y, eps) {
+				continue
+			}
+		}
+		return false, fmt.Sprintf("Token %v differs: expected %s, found %s", i+1,
+			clip(string(ans[i]), 0, sideWidth), clip(string(out[i]), 0, sideWidth)), i
+	}
+	if len(out) != len(ans) {
+		return false, fmt.Sprintf("Expected %v tokens, found %v", len(ans), len(out)), min(len(out), len(ans))
+	}
+	return true, "", -1
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompareFloat(t *testing.T) {
+	tests := []struct {
+		output, answer string
+		eps            float64
+		ok             bool
+		message        string
+		at             int
+	}{
+		{"1.0000001\n", "1\n", 1e-6, true, "", -1},
+		{"1.00001\n", "1\n", 1e-6, false, "Token 1 differs: expected 1, found 1.00001", 0},
+		{"1000001 2\n", "1000000 2\n", 1e-6, false, "Token 1 differs: expected 1000000, found 1000001", 0},
+		{"1000001.0 2\n", "1000000 2\n", 1e-6, true, "", -1},
+		{"1e6\n", "1000000\n", 1e-6, true, "", -1},
+		{"0x10\n", "16.0\n", 1e-6, false, "Token 1 differs: expected 16.0, found 0x10", 0},
+		{"inf\n", "1e400\n", 1e-6, false, "Token 1 differs: expected 1e400, found inf", 0},
+		{"0.5 YES\n", "0.5 NO\n", 1e-6, false, "Token 2 differs: expected NO, found YES", 1},
+		{"1 2\n3\n", "1 2 3", 1e-6, true, "", -1},
+		{"1 2", "1 2 3", 1e-6, false, "Expected 3 tokens, found 2", 2},
+		{"1 2 3 4", "1 2 3", 1e-6, false, "Expected 3 tokens, found 4", 3},
+		{"", "", 1e-6, true, "", -1},
+		{"-0.0000001", "0", 1e-6, true, "", -1},
+		{"nan", "nan", 1e-6, true, "", -1},
+		{"nan", "0", 1e-6, false, "Token 1 differs: expected 0, found nan", 0},
+		{"1.5 " + strings.Repeat("a", 60), "1.5 " + strings.Repeat("b", 60), 1e-6, false,
+			"Token 2 differs: expected " + strings.Repeat("b", 47) + "..., found " + strings.Repeat("a", 47) + "...", 1},
+	}
+	for _, test := range tests {
+		ok, message, at := compareFloat([]byte(test.output), []byte(test.answer), test.eps)
+		if ok != test.ok || message != test.message || at != test.at {
+			t.Errorf("compareFloat(%q, %q, %v) = %v, %q, %v, want %v, %q, %v",
+				test.output, test.answer, test.eps, ok, message, at, test.ok, test.message, test.at)
+		}
+	}
+}
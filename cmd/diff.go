@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+const (
+	// diffContext lines shown around the first mismatching line
+	diffContext = 3
+	// reportLines at most so many lines of the input, the output and the
+	// answer are printed
+	reportLines = 20
+	// reportWidth long lines are clipped to so many characters
+	reportWidth = 120
+	// sideWidth the width of a column of the side-by-side diff
+	sideWidth = 50
+)
+
+// mismatch the first difference between the output and the answer. Line and
+// Token are 0-based. Token is -1 if one of them has no such line
+type mismatch struct {
+	Line   int
+	Token  int
+	output []string
+	answer []string
+	// lines the number of different lines
+	lines int
+}
+
+// splitLines of the plain text, which is nil if the text is empty
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// findMismatch between the plain output and answer. It returns nil if they
+// are the same
+func findMismatch(output, answer string) *mismatch {
+	m := &mismatch{Line: -1, output: splitLines(output), answer: splitLines(answer)}
+	for i := 0; i < max(len(m.output), len(m.answer)); i++ {
+		if i < len(m.output) && i < len(m.answer) && m.output[i] == m.answer[i] {
+			continue
+		}
+		m.lines++
+		if m.Line == -1 {
+			m.Line = i
+		}
+	}
+	if m.Line == -1 {
+		return nil
+	}
+	m.Token = -1
+	if m.Line < len(m.output) && m.Line < len(m.answer) {
+		got, want := strings.Fields(m.output[m.Line]), strings.Fields(m.answer[m.Line])
+		for m.Token = 0; m.Token < len(got) && m.Token < len(want); m.Token++ {
+			if got[m.Token] != want[m.Token] {
+				break
+			}
+		}
+	}
+	return m
+}
+
+// locateToken the line of the k-th token among all tokens of the lines and
+// its index in the line. The line is -1 if there are not so many tokens
+func locateToken(lines []string, k int) (line, token int) {
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if k < len(fields) {
+			return i, k
+		}
+		k -= len(fields)
+	}
+	return -1, -1
+}
+
+// findTokenMismatch the mismatch at the k-th token among all tokens of the
+// plain output and answer, e.g. where compareFloat fails. The token is
+// located in the answer, or in the output if the answer has fewer tokens. It
+// returns nil if neither has so many tokens
+func findTokenMismatch(output, answer string, k int) *mismatch {
+	m := &mismatch{output: splitLines(output), answer: splitLines(answer), lines: 1}
+	if m.Line, m.Token = locateToken(m.answer, k); m.Line == -1 {
+		if m.Line, m.Token = locateToken(m.output, k); m.Line == -1 {
+			return nil
+		}
+	}
+	if m.Line >= len(m.output) || m.Line >= len(m.answer) {
+		m.Token = -1
+	}
+	return m
+}
+
+// token the k-th token of the line, the whole line if k is -1, or a
+// description of why there is not. It is clipped to sideWidth
+func token(lines []string, line, k int) string {
+	if line >= len(lines) {
+		return "end of file"
+	}
+	if k == -1 {
+		return fmt.Sprintf("%q", clip(lines[line], 0, sideWidth))
+	}
+	fields := strings.Fields(lines[line])
+	if k >= len(fields) {
+		return "end of line"
+	}
+	return fmt.Sprintf("%q", clip(fields[k], 0, sideWidth))
+}
+
+// tokenOffset the rune offset of the k-th token of the line
+func tokenOffset(line string, k int) int {
+	inToken := false
+	i := 0
+	for _, c := range line {
+		space := c == ' ' || c == '\t'
+		if !space && !inToken {
+			if k == 0 {
+				return i
+			}
+			k--
+		}
+		inToken = !space
+		i++
+	}
+	return i
+}
+
+// clip the line to width runes around the rune offset. Clipped parts are
+// replaced by "..."
+func clip(line string, offset, width int) string {
+	runes := []rune(line)
+	if len(runes) <= width {
+		return line
+	}
+	start := offset - width/2
+	if start < 0 {
+		start = 0
+	}
+	end := start + width
+	if end > len(runes) {
+		end = len(runes)
+		start = end - width
+	}
+	clipped := runes[start:end]
+	if start > 0 {
+		clipped = append([]rune("..."), clipped[3:]...)
+	}
+	if end < len(runes) {
+		clipped = append(clipped[:len(clipped)-3:len(clipped)-3], []rune("...")...)
+	}
+	return string(clipped)
+}
+
+// truncate the text to reportLines lines of at most reportWidth characters
+func truncate(text string) string {
+	lines := splitLines(text)
+	more := len(lines) - reportLines
+	if more > 0 {
+		lines = lines[:reportLines]
+	}
+	for i, line := range lines {
+		lines[i] = clip(line, 0, reportWidth)
+	}
+	if more > 0 {
+		lines = append(lines, fmt.Sprintf("... %v more lines", more))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// summary where the output differs from the answer first
+func (m *mismatch) summary() string {
+	text := fmt.Sprintf("Line %v differs", m.Line+1)
+	if m.Token >= 0 {
+		text += fmt.Sprintf(" at token %v", m.Token+1)
+	}
+	text += fmt.Sprintf(": read %v, expected %v",
+		token(m.output, m.Line, m.Token), token(m.answer, m.Line, m.Token))
+	if m.lines > 1 {
+		text += fmt.Sprintf(" (%v lines differ)", m.lines)
+	}
+	return text
+}
+
+// paint colors the text when colored, which is false for the reports in
+// json or junit
+type paint func(attr color.Attribute, text string) string
+
+func colored(attr color.Attribute, text string) string {
+	return color.New(attr).Sprint(text)
+}
+
+func uncolored(attr color.Attribute, text string) string {
+	return text
+}
+
+// contextRange the lines shown around the first mismatching line
+func (m *mismatch) contextRange() (int, int) {
+	from, to := m.Line-diffContext, m.Line+diffContext+1
+	if from < 0 {
+		from = 0
+	}
+	return from, min(to, max(len(m.output), len(m.answer)))
+}
+
+// lineAt the line clipped to width around the mismatching token if it is the
+// first mismatching line
+func (m *mismatch) lineAt(lines []string, i, width int) (string, bool) {
+	if i >= len(lines) {
+		return "", false
+	}
+	offset := 0
+	if i == m.Line && m.Token >= 0 {
+		offset = tokenOffset(lines[i], m.Token)
+	}
+	return clip(lines[i], offset, width), true
+}
+
+// unified diff around the first mismatching line. Lines of the output are
+// marked by "-" and lines of the answer by "+"
+func (m *mismatch) unified(p paint) string {
+	var b strings.Builder
+	from, to := m.contextRange()
+	if from > 0 {
+		b.WriteString("...\n")
+	}
+	for i := from; i < to; i++ {
+		got, hasGot := m.lineAt(m.output, i, reportWidth)
+		want, hasWant := m.lineAt(m.answer, i, reportWidth)
+		if hasGot && hasWant && m.output[i] == m.answer[i] {
+			fmt.Fprintf(&b, "  %4v | %v\n", i+1, got)
+			continue
+		}
+		if hasGot {
+			b.WriteString(p(color.FgRed, fmt.Sprintf("- %4v | %v", i+1, got)) + "\n")
+		}
+		if hasWant {
+			b.WriteString(p(color.FgGreen, fmt.Sprintf("+ %4v | %v", i+1, want)) + "\n")
+		}
+	}
+	if to < len(m.output) || to < len(m.answer) {
+		b.WriteString("...\n")
+	}
+	b.WriteString(m.summary())
+	return b.String()
+}
+
+// sideBySide diff around the first mismatching line, with the output on the
+// left and the answer on the right
+func (m *mismatch) sideBySide(p paint) string {
+	var b strings.Builder
+	row := func(mark, number, got, want string) string {
+		return fmt.Sprintf("%v %4v | %-*v | %v", mark, number, sideWidth, got, want)
+	}
+	b.WriteString(row(" ", "", "OUTPUT", "ANSWER") + "\n")
+	from, to := m.contextRange()
+	if from > 0 {
+		b.WriteString(row(" ", "", "...", "...") + "\n")
+	}
+	for i := from; i < to; i++ {
+		got, hasGot := m.lineAt(m.output, i, sideWidth)
+		want, hasWant := m.lineAt(m.answer, i, sideWidth)
+		if hasGot && hasWant && m.output[i] == m.answer[i] {
+			b.WriteString(row(" ", fmt.Sprint(i+1), got, want) + "\n")
+			continue
+		}
+		b.WriteString(p(color.FgRed, row("!", fmt.Sprint(i+1), got, want)) + "\n")
+	}
+	if to < len(m.output) || to < len(m.answer) {
+		b.WriteString(row(" ", "", "...", "...") + "\n")
+	}
+	b.WriteString(m.summary())
+	return b.String()
+}
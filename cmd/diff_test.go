@@ -0,0 +1,109 @@
+package cmd
+
+import "testing"
+
+func TestFindMismatch(t *testing.T) {
+	tests := []struct {
+		output, answer string
+		// line and token of the mismatch, line is -1 if there is none
+		line, token, lines int
+	}{
+		{"1 2\n3\n", "1 2\n3\n", -1, 0, 0},
+		{"", "", -1, 0, 0},
+		{"1 2\n3\n", "1 5\n3\n", 0, 1, 1},
+		{"1\n2\n3\n", "1\n4\n5\n", 1, 0, 2},
+		{"1 2\n", "1 2 3\n", 0, 2, 1},
+		{"1\n", "1\n2\n", 1, -1, 1},
+		{"1\n2\n", "1\n", 1, -1, 1},
+		{"", "1\n", 0, -1, 1},
+	}
+	for _, test := range tests {
+		m := findMismatch(test.output, test.answer)
+		if test.line == -1 {
+			if m != nil {
+				t.Errorf("findMismatch(%q, %q) = line %v, want nil", test.output, test.answer, m.Line)
+			}
+			continue
+		}
+		if m == nil {
+			t.Errorf("findMismatch(%q, %q) = nil, want line %v", test.output, test.answer, test.line)
+			continue
+		}
+		if m.Line != test.line || m.Token != test.token || m.lines != test.lines {
+			t.Errorf("findMismatch(%q, %q) = line %v token %v lines %v, want line %v token %v lines %v",
+				test.output, test.answer, m.Line, m.Token, m.lines, test.line, test.token, test.lines)
+		}
+	}
+}
+
+func TestFindTokenMismatch(t *testing.T) {
+	tests := []struct {
+		output, answer string
+		k              int
+		// line and token of the mismatch, line is -1 if there is none
+		line, token int
+	}{
+		{"0.1 0.2\n0.3 0.5\n", "0.1000001 0.2\n0.3 0.4\n", 3, 1, 1},
+		{"1 2\n", "1 2\n3\n", 2, 1, -1},
+		{"1 2\n3 4\n", "1 2\n3\n", 3, 1, 1},
+		{"1 2\n", "1 2\n", 2, -1, 0},
+	}
+	for _, test := range tests {
+		m := findTokenMismatch(test.output, test.answer, test.k)
+		if test.line == -1 {
+			if m != nil {
+				t.Errorf("findTokenMismatch(%q, %q, %v) = line %v, want nil", test.output, test.answer, test.k, m.Line)
+			}
+			continue
+		}
+		if m == nil {
+			t.Errorf("findTokenMismatch(%q, %q, %v) = nil, want line %v", test.output, test.answer, test.k, test.line)
+			continue
+		}
+		if m.Line != test.line || m.Token != test.token {
+			t.Errorf("findTokenMismatch(%q, %q, %v) = line %v token %v, want line %v token %v",
+				test.output, test.answer, test.k, m.Line, m.Token, test.line, test.token)
+		}
+	}
+}
+
+func TestClip(t *testing.T) {
+	tests := []struct {
+		line          string
+		offset, width int
+		clipped       string
+	}{
+		{"abcdef", 0, 10, "abcdef"},
+		{"abcdef", 0, 6, "abcdef"},
+		{"abcdefghij", 0, 6, "abc..."},
+		{"abcdefghij", 9, 6, "...hij"},
+		{"abcdefghijklmn", 7, 8, "...gh..."},
+		{"αβγδεζηθικ", 0, 6, "αβγ..."},
+		{"αβγδεζηθικ", 9, 6, "...θικ"},
+		{"αβγδεζ", 0, 6, "αβγδεζ"},
+	}
+	for _, test := range tests {
+		if clipped := clip(test.line, test.offset, test.width); clipped != test.clipped {
+			t.Errorf("clip(%q, %v, %v) = %q, want %q", test.line, test.offset, test.width, clipped, test.clipped)
+		}
+	}
+}
+
+func TestTokenOffset(t *testing.T) {
+	tests := []struct {
+		line   string
+		k      int
+		offset int
+	}{
+		{"1 22 333", 0, 0},
+		{"1 22 333", 2, 5},
+		{"  1\t2", 1, 4},
+		{"1 2", 5, 3},
+		{"αβ γδ", 1, 3},
+	}
+	for _, test := range tests {
+		if offset := tokenOffset(test.line, test.k); offset != test.offset {
+			t.Errorf("tokenOffset(%q, %v) = %v, want %v", test.line, test.k, offset, test.offset)
+		}
+	}
+}
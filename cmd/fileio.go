@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// checkStandardIO the commands which run codes by runCode pass the input and
+// the output through the standard input and output only
+func checkStandardIO(name string, inputFile, outputFile string) error {
+	if inputFile != "" || outputFile != "" {
+		return fmt.Errorf("%v doesn't support problems reading or writing files", name)
+	}
+	return nil
+}
+
+// useFileIO runs cmd in a temporary folder for problems which read and write
+// files instead of the standard input and output. The input of the sample is
+// copied into the input file there. The caller removes the folder
+func useFileIO(cmd *exec.Cmd, conf judgeConfig, inPath string) (string, error) {
+	dir, err := os.MkdirTemp("", "cf-test-")
+	if err != nil {
+		return "", err
+	}
+	if conf.InputFile != "" {
+		if err = copyFile(inPath, filepath.Join(dir, conf.InputFile)); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	// The program and the code in the command are relative to current folder,
+	// such as "./a.out" or "a.py", so they are made absolute. Other arguments
+	// are kept as they are
+	if !filepath.IsAbs(cmd.Path) && strings.ContainsRune(cmd.Path, filepath.Separator) {
+		if cmd.Path, err = filepath.Abs(cmd.Path); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	for i := 1; i < len(cmd.Args); i++ {
+		if filepath.IsAbs(cmd.Args[i]) || !slices.Contains(conf.Sources, cmd.Args[i]) {
+			continue
+		}
+		if abs, err := filepath.Abs(cmd.Args[i]); err == nil {
+			cmd.Args[i] = abs
+		}
+	}
+	cmd.Dir = dir
+	return dir, nil
+}
@@ -35,8 +35,14 @@ func readTemplateSource(path string, cln *client.Client) (source string, err err
 	return
 }
 
+// gen saves the source in currentPath. The code is named after the problem
+// in problem.json, or after the folder if there is no such problem
 func gen(source, currentPath, ext string) error {
-	path := filepath.Join(currentPath, filepath.Base(currentPath))
+	name := filepath.Base(currentPath)
+	if problem, err := client.LoadProblem(currentPath); err == nil && problem.ProblemID != "" {
+		name = strings.ToLower(problem.ProblemID)
+	}
+	path := filepath.Join(currentPath, name)
 
 	savePath := path + ext
 	i := 1
@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/fatih/color"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/renderer"
+	"github.com/olekukonko/tablewriter/tw"
+)
+
+const (
+	// historyPath where the results of "cf test" are recorded
+	historyPath = "~/.cf/history"
+	// historyShown the number of the latest runs shown by "cf history"
+	historyShown = 20
+	// historyRuns the number of the latest runs kept for each problem
+	historyRuns = 200
+)
+
+// historySample the result of a sample in a run
+type historySample struct {
+	ID      string  `json:"id"`
+	Verdict string  `json:"verdict"`
+	Time    float64 `json:"time"`
+	Memory  uint64  `json:"memory"`
+}
+
+// historyRun a run of "cf test". Hash is the sha256 of the source, whose copy
+// is kept in the folder of the problem in the history
+type historyRun struct {
+	Time    time.Time       `json:"time"`
+	Problem string          `json:"problem"`
+	Path    string          `json:"path"`
+	File    string          `json:"file"`
+	Hash    string          `json:"hash"`
+	Full    bool            `json:"full"`
+	Samples []historySample `json:"samples"`
+}
+
+func (h *historyRun) passed() int {
+	passed := 0
+	for _, s := range h.Samples {
+		if s.Verdict == verdictPassed {
+			passed++
+		}
+	}
+	return passed
+}
+
+// source where the copy of the source of the run is kept
+func (h *historyRun) source(dir string) string {
+	return filepath.Join(dir, h.Hash+filepath.Ext(h.File))
+}
+
+// historyDir of the problem in current folder, which is keyed on the path
+func historyDir() (string, error) {
+	root, err := homedir.Expand(historyPath)
+	if err != nil {
+		return "", err
+	}
+	path, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256([]byte(path))
+	return filepath.Join(root, hex.EncodeToString(h[:8])), nil
+}
+
+// recordHistory appends the results of the code to the history of the problem,
+// and prunes the history
+func recordHistory(filename string, results []*sampleResult) error {
+	dir, err := historyDir()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	hash, err := hashFile(filename)
+	if err != nil {
+		return err
+	}
+	path, _ := os.Getwd()
+	run := historyRun{
+		Time: time.Now(),
+		Path: path,
+		File: filepath.Base(filename),
+		Hash: hash,
+		Full: Args.Full,
+	}
+	if Args.Info.ProblemID != "" {
+		run.Problem = Args.Info.Hint()
+	}
+	for _, r := range results {
+		run.Samples = append(run.Samples, historySample{r.ID, r.Verdict, r.Time, r.Memory})
+	}
+	if _, err = os.Stat(run.source(dir)); err != nil {
+		if err = copyFile(filename, run.source(dir)); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(dir, "runs.jsonl"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return pruneHistory(dir)
+}
+
+// pruneHistory removes the runs beyond the latest historyRuns, and the copies
+// of the sources which no run left refers to
+func pruneHistory(dir string) error {
+	runs, err := loadHistory(dir)
+	if err != nil || len(runs) <= historyRuns {
+		return err
+	}
+	runs = runs[len(runs)-historyRuns:]
+	var b bytes.Buffer
+	sources := map[string]bool{}
+	for _, run := range runs {
+		data, err := json.Marshal(run)
+		if err != nil {
+			return err
+		}
+		b.Write(append(data, '\n'))
+		sources[filepath.Base(run.source(dir))] = true
+	}
+	// Replaced by renaming, so an interrupted prune doesn't lose the history
+	tmp := filepath.Join(dir, "runs.jsonl.tmp")
+	if err = os.WriteFile(tmp, b.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, filepath.Join(dir, "runs.jsonl")); err != nil {
+		return err
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if name := file.Name(); name != "runs.jsonl" && !sources[name] {
+			os.Remove(filepath.Join(dir, name))
+		}
+	}
+	return nil
+}
+
+// loadHistory all runs of the problem in current folder, oldest first
+func loadHistory(dir string) (runs []*historyRun, err error) {
+	file, err := os.Open(filepath.Join(dir, "runs.jsonl"))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	broken := 0
+	for scanner.Scan() {
+		run := &historyRun{}
+		// A broken line is skipped, e.g. a run interrupted while writing or a
+		// hand-edited one
+		if json.Unmarshal(scanner.Bytes(), run) != nil || !run.valid() {
+			broken++
+			continue
+		}
+		runs = append(runs, run)
+	}
+	if broken > 0 {
+		color.Yellow("Skip %v broken runs in %v", broken, file.Name())
+	}
+	return runs, scanner.Err()
+}
+
+// valid whether the hash is a sha256, which names the copy of the source
+func (h *historyRun) valid() bool {
+	b, err := hex.DecodeString(h.Hash)
+	return err == nil && len(b) == sha256.Size
+}
+
+// regression a sample which passed in run Before and failed in run After of
+// the same file
+type regression struct {
+	ID     string
+	Before int
+	After  int
+}
+
+// findRegressions in the runs. Only runs where the source changed count,
+// since failing again with the same source is flaky rather than broken
+func findRegressions(runs []*historyRun) []regression {
+	regressions := []regression{}
+	last := map[string]int{}
+	for i, run := range runs {
+		prev, ok := last[run.File]
+		last[run.File] = i
+		if !ok || runs[prev].Hash == run.Hash {
+			continue
+		}
+		passed := map[string]bool{}
+		for _, s := range runs[prev].Samples {
+			passed[s.ID] = s.Verdict == verdictPassed
+		}
+		for _, s := range run.Samples {
+			if passed[s.ID] && s.Verdict != verdictPassed {
+				regressions = append(regressions, regression{s.ID, prev, i})
+			}
+		}
+	}
+	return regressions
+}
+
+// History command
+func History() (err error) {
+	shown := historyShown
+	if Args.Count != "" {
+		if shown, err = strconv.Atoi(Args.Count); err != nil || shown < 1 {
+			return fmt.Errorf("Invalid count %v", Args.Count)
+		}
+	}
+	dir, err := historyDir()
+	if err != nil {
+		return
+	}
+	all, err := loadHistory(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return
+	}
+	runs := []*historyRun{}
+	for _, run := range all {
+		if run.Full == Args.Full {
+			runs = append(runs, run)
+		}
+	}
+	if len(runs) == 0 {
+		return errors.New("There is no history of current problem. It's recorded by `cf test`")
+	}
+	if runs[len(runs)-1].Problem != "" {
+		color.Cyan("History of %v", runs[len(runs)-1].Problem)
+	}
+
+	from := max(len(runs)-shown, 0)
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, run := range runs[from:] {
+		for _, s := range run.Samples {
+			if !seen[s.ID] {
+				seen[s.ID] = true
+				ids = append(ids, s.ID)
+			}
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return lessNatural(ids[i], ids[j])
+	})
+
+	table := tablewriter.NewTable(color.Output,
+		tablewriter.WithRenderer(renderer.NewBlueprint(tw.Rendition{
+			Symbols: tw.NewSymbols(tw.StyleASCII),
+		})),
+		tablewriter.WithHeaderAutoFormat(tw.Off),
+	)
+	header := []string{"RUN", "TIME", "FILE", "HASH", "PASSED"}
+	for _, id := range ids {
+		header = append(header, "#"+id)
+	}
+	table.Header(header)
+	for i := from; i < len(runs); i++ {
+		run := runs[i]
+		row := []string{
+			fmt.Sprint(i + 1),
+			run.Time.Format("01-02 15:04:05"),
+			run.File,
+			run.Hash[:8],
+			fmt.Sprintf("%v/%v", run.passed(), len(run.Samples)),
+		}
+		verdicts := map[string]string{}
+		for _, s := range run.Samples {
+			verdicts[s.ID] = shortVerdicts[s.Verdict]
+			if verdicts[s.ID] == "" {
+				verdicts[s.ID] = "ERR"
+			}
+		}
+		for _, id := range ids {
+			if v, ok := verdicts[id]; ok {
+				row = append(row, v)
+			} else {
+				row = append(row, "-")
+			}
+		}
+		table.Append(row)
+	}
+	if err = table.Render(); err != nil {
+		return
+	}
+
+	for _, r := range findRegressions(runs) {
+		if r.After < from {
+			continue
+		}
+		before, after := runs[r.Before], runs[r.After]
+		color.Red("#%v passed in run %v but failed in run %v", r.ID, r.Before+1, r.After+1)
+		fmt.Printf("  diff %v %v\n", before.source(dir), after.source(dir))
+	}
+	return nil
+}
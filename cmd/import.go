@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// testFile a file of a test in a directory or in a zip
+type testFile struct {
+	name string
+	open func() (io.ReadCloser, error)
+}
+
+// importedTest an input and its answer, answer is nil if there is no answer
+type importedTest struct {
+	key    string
+	input  *testFile
+	answer *testFile
+}
+
+var (
+	// Polygon packages: tests/01 and tests/01.a
+	polygonInputReg  = regexp.MustCompile(`(^|/)tests/\d+$`)
+	polygonAnswerReg = regexp.MustCompile(`(^|/)tests/\d+\.a$`)
+	// 1.in and 1.out, 1.ans or 1.a
+	inputExtReg  = regexp.MustCompile(`\.in$`)
+	answerExtReg = regexp.MustCompile(`\.(out|ans|a)$`)
+	// runs of digits and of other characters, see lessNatural
+	naturalReg = regexp.MustCompile(`\d+|\D+`)
+)
+
+// listFiles in a zip or a directory. Names are slash separated paths relative
+// to the root
+func listFiles(source string) (files []*testFile, closer io.Closer, err error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return
+	}
+	if !info.IsDir() {
+		r, err := zip.OpenReader(source)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, f := range r.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			files = append(files, &testFile{f.Name, f.Open})
+		}
+		return files, r, nil
+	}
+	err = filepath.Walk(source, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(source, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, &testFile{filepath.ToSlash(rel), func() (io.ReadCloser, error) {
+			return os.Open(p)
+		}})
+		return nil
+	})
+	return
+}
+
+// lessNatural compares strings with their numbers compared by value
+func lessNatural(a, b string) bool {
+	x, y := naturalReg.FindAllString(a, -1), naturalReg.FindAllString(b, -1)
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if x[i] == y[i] {
+			continue
+		}
+		m, errM := strconv.Atoi(x[i])
+		n, errN := strconv.Atoi(y[i])
+		if errM == nil && errN == nil && m != n {
+			return m < n
+		}
+		return x[i] < y[i]
+	}
+	return len(x) < len(y)
+}
+
+// pairTests finds the inputs and their answers. Polygon's layout is used if
+// there is any test in it
+func pairTests(files []*testFile) []*importedTest {
+	tests := map[string]*importedTest{}
+	answers := map[string]*testFile{}
+	polygon := false
+	for _, f := range files {
+		if polygonInputReg.MatchString(f.name) {
+			polygon = true
+		}
+	}
+	for _, f := range files {
+		if polygon {
+			if polygonInputReg.MatchString(f.name) {
+				tests[f.name] = &importedTest{key: f.name, input: f}
+			} else if polygonAnswerReg.MatchString(f.name) {
+				answers[strings.TrimSuffix(f.name, ".a")] = f
+			}
+			continue
+		}
+		ext := path.Ext(f.name)
+		key := strings.TrimSuffix(f.name, ext)
+		if inputExtReg.MatchString(f.name) {
+			tests[key] = &importedTest{key: key, input: f}
+		} else if answerExtReg.MatchString(f.name) {
+			answers[key] = f
+		}
+	}
+	result := []*importedTest{}
+	for key, test := range tests {
+		test.answer = answers[key]
+		result = append(result, test)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return lessNatural(result[i].key, result[j].key)
+	})
+	return result
+}
+
+func copyTestFile(f *testFile, dst string) error {
+	in, err := f.open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// replaceDir replaces the folder dst with src. dst is kept if it fails
+func replaceDir(src, dst string) error {
+	if _, err := os.Stat(dst); os.IsNotExist(err) {
+		return os.Rename(src, dst)
+	}
+	old := src + ".old"
+	if err := os.Rename(dst, old); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err != nil {
+		os.Rename(old, dst)
+		return err
+	}
+	return os.RemoveAll(old)
+}
+
+// ImportTests command
+func ImportTests() (err error) {
+	files, closer, err := listFiles(Args.Source)
+	if err != nil {
+		return
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+	tests := pairTests(files)
+	if len(tests) == 0 {
+		return fmt.Errorf("Cannot find any test in %v", Args.Source)
+	}
+	dir := testDir()
+	next := 1
+	if Args.Full {
+		// The full tests are replaced as a whole. They are imported into a
+		// temporary folder first, so the old ones are kept if it fails
+		if dir, err = os.MkdirTemp(".", fullTestsDir+"-*"); err != nil {
+			return
+		}
+		defer os.RemoveAll(dir)
+		if err = os.Chmod(dir, 0755); err != nil {
+			return
+		}
+	} else if next, err = strconv.Atoi(nextSampleID()); err != nil {
+		return
+	}
+
+	missing := 0
+	ids := []string{}
+	for i := range tests {
+		ids = append(ids, fmt.Sprint(next+i))
+	}
+	if err = dropSampleTests(ids...); err != nil {
+		return
+	}
+	for i, test := range tests {
+		id := ids[i]
+		if err = copyTestFile(test.input, filepath.Join(dir, fmt.Sprintf("in%v.txt", id))); err != nil {
+			return
+		}
+		if test.answer == nil {
+			missing++
+			continue
+		}
+		if err = copyTestFile(test.answer, filepath.Join(dir, fmt.Sprintf("ans%v.txt", id))); err != nil {
+			return
+		}
+	}
+	if Args.Full {
+		if err = replaceDir(dir, fullTestsDir); err != nil {
+			return
+		}
+	}
+	color.Green("Imported %v tests as %v ~ %v", len(tests),
+		inputPath(fmt.Sprint(next)), inputPath(fmt.Sprint(next+len(tests)-1)))
+	if missing > 0 {
+		hint := "cf testcase gen-ans"
+		if Args.Full {
+			hint += " --full"
+		}
+		color.Yellow("%v inputs have no answers. Run `%v` to generate them", missing, hint)
+	}
+	return nil
+}
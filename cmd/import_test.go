@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLessNatural(t *testing.T) {
+	tests := []struct {
+		a, b string
+		less bool
+	}{
+		{"2", "10", true},
+		{"10", "2", false},
+		{"tests/9", "tests/10", true},
+		{"a2.in", "a10.in", true},
+		{"a.in", "b.in", true},
+		{"1", "1", false},
+		{"01", "1", true},
+		{"1", "1a", true},
+		{"1a", "1", false},
+		{"x1y2", "x1y10", true},
+	}
+	for _, test := range tests {
+		if less := lessNatural(test.a, test.b); less != test.less {
+			t.Errorf("lessNatural(%q, %q) = %v, want %v", test.a, test.b, less, test.less)
+		}
+	}
+}
+
+func TestPairTests(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		// pairs the names of the input and the answer of each test, where an
+		// empty answer means there is none
+		pairs [][2]string
+	}{
+		{"extensions", []string{"2.in", "10.in", "1.in", "1.out", "2.ans", "10.a", "readme.txt"},
+			[][2]string{{"1.in", "1.out"}, {"2.in", "2.ans"}, {"10.in", "10.a"}}},
+		{"missing answer", []string{"a.in", "b.in", "b.out", "c.out"},
+			[][2]string{{"a.in", ""}, {"b.in", "b.out"}}},
+		{"polygon", []string{"tests/02", "tests/02.a", "tests/10", "tests/10.a", "tests/01", "statements/1.in"},
+			[][2]string{{"tests/01", ""}, {"tests/02", "tests/02.a"}, {"tests/10", "tests/10.a"}}},
+		{"nested polygon", []string{"pkg/tests/1", "pkg/tests/1.a"},
+			[][2]string{{"pkg/tests/1", "pkg/tests/1.a"}}},
+		{"nothing", []string{"readme.txt"}, [][2]string{}},
+	}
+	for _, test := range tests {
+		files := []*testFile{}
+		for _, name := range test.files {
+			files = append(files, &testFile{name: name})
+		}
+		pairs := [][2]string{}
+		for _, imported := range pairTests(files) {
+			pair := [2]string{imported.input.name, ""}
+			if imported.answer != nil {
+				pair[1] = imported.answer.name
+			}
+			pairs = append(pairs, pair)
+		}
+		if !reflect.DeepEqual(pairs, test.pairs) {
+			t.Errorf("%v: pairTests = %v, want %v", test.name, pairs, test.pairs)
+		}
+	}
+}
+
+func TestReplaceDir(t *testing.T) {
+	root := t.TempDir()
+	src, dst := filepath.Join(root, "src"), filepath.Join(root, "dst")
+	for _, dir := range []string{src, dst} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(src, "in1.txt"), []byte("new"), 0644)
+	os.WriteFile(filepath.Join(dst, "in1.txt"), []byte("old"), 0644)
+	os.WriteFile(filepath.Join(dst, "in2.txt"), []byte("old"), 0644)
+	if err := replaceDir(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(root)
+	if len(entries) != 1 || entries[0].Name() != "dst" {
+		t.Errorf("replaceDir left %v, want only dst", entries)
+	}
+	entries, _ = os.ReadDir(dst)
+	if len(entries) != 1 {
+		t.Errorf("replaceDir kept %v, want only in1.txt", entries)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dst, "in1.txt")); string(b) != "new" {
+		t.Errorf("in1.txt = %q, want %q", b, "new")
+	}
+
+	// dst doesn't exist
+	other := filepath.Join(root, "other")
+	if err := replaceDir(dst, other); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(other, "in1.txt")); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// interactorGrace how long the interactor may run after the solution exited,
+// and the other way around
+const interactorGrace = 5 * time.Second
+
+// transcript records the communication between the solution and the
+// interactor. Each line is prefixed by its direction
+type transcript struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// forwarder writes everything to w and logs it into the transcript
+type forwarder struct {
+	w      io.Writer
+	log    *transcript
+	prefix string
+	// middle whether the last write didn't end with a newline
+	middle bool
+}
+
+func (f *forwarder) Write(p []byte) (int, error) {
+	f.log.mu.Lock()
+	for _, line := range bytes.SplitAfter(p, []byte{'\n'}) {
+		if len(line) == 0 {
+			continue
+		}
+		if !f.middle {
+			f.log.buf.WriteString(f.prefix)
+		}
+		f.log.buf.Write(line)
+		f.middle = line[len(line)-1] != '\n'
+	}
+	f.log.mu.Unlock()
+	return f.w.Write(p)
+}
+
+// runInteractive judges a sample of an interactive problem. The standard
+// output of the solution is connected to the standard input of the interactor
+// and vice versa. The interactor is run as "<interactor> <input> <output>
+// <answer>" and its exit code is the verdict, like a checker
+func runInteractive(sampleID string, conf judgeConfig, stderr io.Writer) (*sampleResult, error) {
+	inPath := inputPath(sampleID)
+	ansPath := answerPath(sampleID)
+	if _, err := os.Stat(inPath); err != nil {
+		return &sampleResult{ID: sampleID, Verdict: verdictFailed, Message: err.Error()}, nil
+	}
+	outFile, err := os.CreateTemp("", "cf-interactor-*.txt")
+	if err != nil {
+		return nil, err
+	}
+	outFile.Close()
+	defer os.Remove(outFile.Name())
+
+	// solution -> interactor
+	interactorIn, solutionOut, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	// interactor -> solution
+	solutionIn, interactorOut, err := os.Pipe()
+	if err != nil {
+		interactorIn.Close()
+		solutionOut.Close()
+		return nil, err
+	}
+	defer interactorIn.Close()
+	defer solutionIn.Close()
+	log := &transcript{}
+	r := &sampleResult{ID: sampleID, interactive: true}
+
+	var message bytes.Buffer
+	cmds := splitCmd(conf.Interactor)
+	interactor := exec.Command(cmds[0], append(cmds[1:], inPath, outFile.Name(), ansPath)...)
+	interactor.Stdin = interactorIn
+	interactor.Stdout = &forwarder{w: interactorOut, log: log, prefix: "< "}
+	interactor.Stderr = &message
+	setProcessGroup(interactor)
+	if err := interactor.Start(); err != nil {
+		solutionOut.Close()
+		interactorOut.Close()
+		r.Verdict, r.Message = verdictInteractorFailed, err.Error()
+		return r, nil
+	}
+	// The standard input of the solution is closed once the interactor
+	// exited
+	var interactorErr error
+	exited := make(chan struct{})
+	go func() {
+		interactorErr = waitProcess(interactor)
+		interactorOut.Close()
+		close(exited)
+	}()
+	// The solution is killed if it doesn't exit in time after the interactor,
+	// e.g. it keeps waiting for the input
+	stop := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-exited:
+		case <-finished:
+			return
+		}
+		select {
+		case <-time.After(interactorGrace):
+			close(stop)
+		case <-finished:
+		}
+	}()
+
+	cmds = splitCmd(conf.Command)
+	cmd := exec.Command(cmds[0], cmds[1:]...)
+	cmd.Stdin = solutionIn
+	cmd.Stdout = &forwarder{w: solutionOut, log: log, prefix: "> "}
+	e, err := executeUntil(cmd, conf.Limit, stderr, stop)
+	close(finished)
+	solutionOut.Close()
+
+	select {
+	case <-exited:
+	case <-time.After(interactorGrace):
+		killProcessTree(interactor)
+		<-exited
+		if err == nil && e.verdict() == "" {
+			r.Verdict, r.Message = verdictInteractorFailed, "it didn't exit after the solution"
+			return r, nil
+		}
+	}
+
+	if err == errInterrupted {
+		return nil, err
+	}
+	if err != nil {
+		r.Verdict, r.Message = verdictRuntimeError, err.Error()
+		return r, nil
+	}
+	r.measure(e)
+	if r.Verdict = e.verdict(); r.Verdict != "" {
+		return r, nil
+	}
+
+	ok := interactorErr == nil
+	if exitErr, isExit := interactorErr.(*exec.ExitError); isExit {
+		switch exitErr.ExitCode() {
+		case 1, 2:
+		default:
+			r.Verdict = verdictInteractorFailed
+			r.Message = fmt.Sprintf("%v %v", interactorErr.Error(), strings.TrimSpace(message.String()))
+			return r, nil
+		}
+	} else if interactorErr != nil {
+		r.Verdict, r.Message = verdictInteractorFailed, interactorErr.Error()
+		return r, nil
+	}
+	if ok && e.Stopped {
+		r.Verdict, r.Message = verdictRuntimeError, "it didn't exit after the interactor"
+		return r, nil
+	}
+	if ok && e.Err != nil {
+		r.Verdict, r.Message = verdictRuntimeError, e.Err.Error()
+		return r, nil
+	}
+	if ok && conf.Checker != "" {
+		output, err := os.ReadFile(outFile.Name())
+		if err != nil {
+			return nil, err
+		}
+		var checkerMessage string
+		ok, checkerMessage, err = check(conf.Checker, inPath, ansPath, output, conf.Limit)
+		if err != nil {
+			r.Verdict, r.Message = verdictCheckerFailed, err.Error()
+			return r, nil
+		}
+		message.WriteString("\n" + checkerMessage)
+	}
+
+	if ok {
+		r.Verdict = verdictPassed
+		return r, nil
+	}
+	input, err := os.ReadFile(inPath)
+	if err != nil {
+		return nil, err
+	}
+	r.Verdict = verdictFailed
+	r.Input = string(input)
+	r.Transcript = log.buf.String()
+	r.Message = strings.TrimSpace(message.String())
+	return r, nil
+}
@@ -0,0 +1,545 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/NetWilliam/cf-tool/util"
+	"golang.org/x/sys/unix"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// rlimitName is argv[0] of cf when it's re-executed to set RLIMIT_AS for a
+// program, and rlimitFailure its exit code when it cannot
+const (
+	rlimitName    = "cf-rlimit"
+	rlimitFailure = 125
+)
+
+// helperEnv tells a helper, i.e. cf re-executed before the program, the
+// socket to report to cf through, see execProgram. watchEnv tells it to
+// watch the allocations of the program by installWatch
+const (
+	helperEnv = "CF_HELPER_FD"
+	watchEnv  = "CF_WATCH_ALLOC"
+)
+
+func init() {
+	if len(os.Args) < 3 || os.Args[0] != rlimitName {
+		return
+	}
+	// The seccomp filter of installWatch is installed for the current thread,
+	// which must be the one calling exec
+	runtime.LockOSThread()
+	err := enterRlimit(os.Args[1], os.Args[2], os.Args[3:])
+	fmt.Fprintf(os.Stderr, "cf rlimit: %v\n", err)
+	os.Exit(rlimitFailure)
+}
+
+// enterRlimit sets RLIMIT_AS and executes the program
+func enterRlimit(memory, path string, args []string) error {
+	limit, err := strconv.ParseUint(memory, 10, 64)
+	if err != nil {
+		return err
+	}
+	return execProgram(limit, path, args)
+}
+
+// execProgram executes the program in a helper with RLIMIT_AS limit, 0 means
+// no limit. If cf asked for it by helperEnv, the cpu time of the helper and
+// the listener of installWatch are reported right before exec, so that cf
+// measures the program only. Nothing may allocate once the filter is
+// installed, since cf cannot answer before it has the listener, nor once the
+// limit is set, since the runtime of cf reserves much more address space
+func execProgram(limit uint64, path string, args []string) error {
+	value, helper := os.LookupEnv(helperEnv)
+	watch := os.Getenv(watchEnv) != ""
+	os.Unsetenv(helperEnv)
+	os.Unsetenv(watchEnv)
+	conn := -1
+	if helper {
+		var err error
+		if conn, err = strconv.Atoi(value); err != nil {
+			return err
+		}
+	}
+	pathp, err := unix.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+	argv, err := cStrings(args)
+	if err != nil {
+		return err
+	}
+	env, err := cStrings(os.Environ())
+	if err != nil {
+		return err
+	}
+	var usage unix.Rusage
+	data := make([]byte, 8)
+	iov := unix.Iovec{Base: &data[0]}
+	iov.SetLen(len(data))
+	oob := unix.UnixRights(0)
+	msg := unix.Msghdr{Iov: &iov, Iovlen: 1}
+	listener := -1
+	if helper && watch {
+		listener = installWatch()
+	}
+	if listener >= 0 {
+		*(*int32)(unsafe.Pointer(&oob[unix.CmsgLen(0)])) = int32(listener)
+		msg.Control = &oob[0]
+		msg.SetControllen(len(oob))
+	}
+	unix.RawSyscall(unix.SYS_GETRUSAGE, unix.RUSAGE_SELF, uintptr(unsafe.Pointer(&usage)), 0)
+	cpu := uint64(usage.Utime.Nano() + usage.Stime.Nano())
+	for i := range data {
+		data[i] = byte(cpu >> (8 * i))
+	}
+	if helper {
+		_, _, errno := unix.RawSyscall(unix.SYS_SENDMSG, uintptr(conn), uintptr(unsafe.Pointer(&msg)), 0)
+		unix.RawSyscall(unix.SYS_CLOSE, uintptr(conn), 0, 0)
+		if listener >= 0 {
+			unix.RawSyscall(unix.SYS_CLOSE, uintptr(listener), 0, 0)
+		}
+		if errno != 0 {
+			return errno
+		}
+	}
+	if limit > 0 {
+		rlimit := unix.Rlimit{Cur: limit, Max: limit}
+		_, _, errno := unix.RawSyscall6(unix.SYS_PRLIMIT64, 0, unix.RLIMIT_AS,
+			uintptr(unsafe.Pointer(&rlimit)), 0, 0, 0)
+		if errno != 0 {
+			return errno
+		}
+	}
+	_, _, errno := unix.RawSyscall(unix.SYS_EXECVE, uintptr(unsafe.Pointer(pathp)),
+		uintptr(unsafe.Pointer(&argv[0])), uintptr(unsafe.Pointer(&env[0])))
+	return errno
+}
+
+// cStrings a NULL terminated array of C strings
+func cStrings(s []string) ([]*byte, error) {
+	p := make([]*byte, len(s)+1)
+	for i, v := range s {
+		b, err := unix.BytePtrFromString(v)
+		if err != nil {
+			return nil, err
+		}
+		p[i] = b
+	}
+	return p, nil
+}
+
+// receiveHelper what execProgram reports: the cpu time of the helper, and
+// the listener of installWatch, -1 if there is none. An error is returned if
+// the helper exited without reporting
+func receiveHelper(conn *os.File) (cpu time.Duration, listener int, err error) {
+	data := make([]byte, 8)
+	oob := make([]byte, unix.CmsgSpace(4))
+	n, oobn, _, _, err := unix.Recvmsg(int(conn.Fd()), data, oob, 0)
+	if err != nil {
+		return 0, -1, err
+	}
+	if n != len(data) {
+		return 0, -1, errors.New("The helper exited without reporting")
+	}
+	for i := range data {
+		cpu |= time.Duration(data[i]) << (8 * i)
+	}
+	listener = -1
+	if messages, err := unix.ParseSocketControlMessage(oob[:oobn]); err == nil && len(messages) == 1 {
+		if fds, err := unix.ParseUnixRights(&messages[0]); err == nil && len(fds) == 1 {
+			listener = fds[0]
+		}
+	}
+	return cpu, listener, nil
+}
+
+// memoryLimiter constrains and measures the memory of a sample. It puts the
+// process into a new cgroup v2 when there is a writable one with the memory
+// controller, otherwise RLIMIT_AS of the process is set before it executes
+type memoryLimiter struct {
+	limit  uint64
+	cgroup string
+	fd     int
+	// child whether the child sets RLIMIT_AS itself, helper whether cf is
+	// re-executed before the program, either for RLIMIT_AS or the sandbox
+	child  bool
+	helper bool
+	// hasPeak whether the cgroup reports the peak memory, otherwise hwm the
+	// max VmHWM of the process is sampled, and vmPeak the max VmPeak. They
+	// are sampled by the watcher of allocations too, so mu guards them
+	hasPeak bool
+	mu      sync.Mutex
+	hwm     uint64
+	vmPeak  uint64
+	// self the executable of cf. The process isn't sampled while it's still
+	// cf, i.e. before it executes the program
+	self string
+	// conn receives the report of execProgram from the helper, which has the
+	// other end connChild. received is closed once helperCPU, the cpu time
+	// of the helper, is known. allocFailed whether an allocation of the
+	// program exceeded RLIMIT_AS. Closing stop ends the watcher, which polls
+	// the other end stopped
+	conn        *os.File
+	connChild   *os.File
+	received    chan struct{}
+	helperCPU   time.Duration
+	allocFailed atomic.Bool
+	stop        *os.File
+	stopped     *os.File
+	watching    sync.WaitGroup
+}
+
+// newMemoryLimiter must be called before cmd starts. limit is in bytes, 0
+// means no limit. The cgroup is used without a limit too, for measuring
+func newMemoryLimiter(cmd *exec.Cmd, limit uint64) *memoryLimiter {
+	m := &memoryLimiter{limit: limit, fd: -1}
+	m.self, _ = os.Readlink("/proc/self/exe")
+	dir, err := createCgroup(limit)
+	if err != nil {
+		return m
+	}
+	fd, err := syscall.Open(dir, syscall.O_RDONLY|syscall.O_DIRECTORY, 0)
+	if err != nil {
+		os.Remove(dir)
+		return m
+	}
+	m.cgroup, m.fd = dir, fd
+	if _, err := os.Stat(filepath.Join(dir, "memory.peak")); err == nil {
+		m.hasPeak = true
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = fd
+	return m
+}
+
+// createCgroup in the cgroup of cf or its parent, whichever we are allowed to
+func createCgroup(limit uint64) (string, error) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return "", errors.New("cgroup v2 is not mounted")
+	}
+	b, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	self := ""
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "0::") {
+			self = filepath.Join(cgroupRoot, line[3:])
+		}
+	}
+	if self == "" {
+		return "", errors.New("Cannot find the cgroup of current process")
+	}
+	for _, parent := range []string{self, filepath.Dir(self)} {
+		if !strings.HasPrefix(parent, cgroupRoot) {
+			continue
+		}
+		controllers, err := os.ReadFile(filepath.Join(parent, "cgroup.subtree_control"))
+		if err != nil || !strings.Contains(string(controllers), "memory") {
+			continue
+		}
+		if unix.Access(filepath.Join(parent, "cgroup.procs"), unix.W_OK) != nil {
+			continue
+		}
+		dir := filepath.Join(parent, "cf-"+util.RandString(8))
+		if err := os.Mkdir(dir, 0755); err != nil {
+			continue
+		}
+		max := "max"
+		if limit > 0 {
+			max = fmt.Sprint(limit)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "memory.max"), []byte(max), 0644); err != nil {
+			os.Remove(dir)
+			continue
+		}
+		os.WriteFile(filepath.Join(dir, "memory.swap.max"), []byte("0"), 0644)
+		return dir, nil
+	}
+	return "", errors.New("Cannot find a writable cgroup with memory controller")
+}
+
+// childRlimit returns the limit of RLIMIT_AS which the child must set by
+// itself before exec, 0 if the cgroup is used. wrap won't set it then
+func (m *memoryLimiter) childRlimit() uint64 {
+	m.helper = true
+	if m.cgroup != "" {
+		return 0
+	}
+	m.child = true
+	return m.limit
+}
+
+// connect makes the helper report to cf by execProgram and watch the
+// allocations of the program, which samples its memory as well. It must be
+// called before cmd starts
+func (m *memoryLimiter) connect(cmd *exec.Cmd) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return
+	}
+	m.conn = os.NewFile(uintptr(fds[0]), "helper")
+	m.connChild = os.NewFile(uintptr(fds[1]), "helper")
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%v=%v", helperEnv, 3+len(cmd.ExtraFiles)))
+	cmd.ExtraFiles = append(cmd.ExtraFiles, m.connChild)
+	if !allocWatchable {
+		return
+	}
+	if m.stopped, m.stop, err = os.Pipe(); err == nil {
+		cmd.Env = append(cmd.Env, watchEnv+"=1")
+	}
+}
+
+// started must be called once cmd has started. It receives the report of the
+// helper and answers the allocations of the program, if they are watched
+func (m *memoryLimiter) started() {
+	if m.conn == nil {
+		return
+	}
+	m.connChild.Close()
+	m.connChild = nil
+	m.received = make(chan struct{})
+	m.watching.Add(1)
+	go func() {
+		defer m.watching.Done()
+		cpu, listener, err := receiveHelper(m.conn)
+		m.helperCPU = cpu
+		close(m.received)
+		if err == nil && listener >= 0 {
+			serveAllocations(listener, int(m.stopped.Fd()), m.grow, m.brk)
+		}
+	}()
+}
+
+// helperTime the cpu time the helper spent before it executed the program,
+// which must not be counted as the time of the program. It must be called
+// after the process exited
+func (m *memoryLimiter) helperTime() time.Duration {
+	if m.received == nil {
+		return 0
+	}
+	<-m.received
+	return m.helperCPU
+}
+
+// grow is called before the address space of pid grows by size bytes. An
+// allocation exceeding RLIMIT_AS fails. The process is sampled meanwhile,
+// which catches fast programs in a helper too
+func (m *memoryLimiter) grow(pid int, size uint64) {
+	status := readStatus(pid)
+	m.record(status)
+	page := uint64(os.Getpagesize())
+	size = (size + page - 1) / page * page
+	if m.child && m.limit > 0 && status["VmSize:"]+size > m.limit {
+		m.allocFailed.Store(true)
+	}
+}
+
+// brk is called before pid moves its program break to addr
+func (m *memoryLimiter) brk(pid int, addr uint64) {
+	page := uint64(os.Getpagesize())
+	addr = (addr + page - 1) / page * page
+	if end := heapEnd(pid); addr > end && end > 0 {
+		m.grow(pid, addr-end)
+		return
+	}
+	m.record(readStatus(pid))
+}
+
+// heapEnd the current program break of pid rounded up to pages, which is the
+// end of the heap, or the start of the heap if there is none yet. 0 if it's
+// unknown
+func heapEnd(pid int) uint64 {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%v/maps", pid))
+	if err != nil {
+		return 0
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasSuffix(line, "[heap]") {
+			continue
+		}
+		_, rest, _ := strings.Cut(line, "-")
+		end, _, _ := strings.Cut(rest, " ")
+		addr, _ := strconv.ParseUint(end, 16, 64)
+		return addr
+	}
+	// start_brk is the 47th field, the 2nd is the name in parentheses
+	b, err = os.ReadFile(fmt.Sprintf("/proc/%v/stat", pid))
+	if err != nil {
+		return 0
+	}
+	i := bytes.LastIndexByte(b, ')')
+	if i < 0 {
+		return 0
+	}
+	fields := strings.Fields(string(b[i+1:]))
+	if len(fields) < 45 {
+		return 0
+	}
+	addr, _ := strconv.ParseUint(fields[44], 10, 64)
+	return addr
+}
+
+// readStatus the fields of /proc/<pid>/status in kB, converted to bytes
+func readStatus(pid int) map[string]uint64 {
+	status := map[string]uint64{}
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%v/status", pid))
+	if err != nil {
+		return status
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || fields[2] != "kB" {
+			continue
+		}
+		if kb, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			status[fields[0]] = kb * 1024
+		}
+	}
+	return status
+}
+
+// wrap makes cmd set RLIMIT_AS before it executes the program, unless the
+// cgroup is used or the sandbox sets it. A helper reports to cf then. It must
+// be called before cmd starts
+func (m *memoryLimiter) wrap(cmd *exec.Cmd) {
+	if cmd.Err != nil {
+		return
+	}
+	if m.limit > 0 && m.cgroup == "" && !m.child {
+		cmd.Args = append([]string{rlimitName, fmt.Sprint(m.limit), cmd.Path}, cmd.Args...)
+		cmd.Path = "/proc/self/exe"
+		m.child, m.helper = true, true
+	}
+	if m.helper {
+		m.connect(cmd)
+	}
+}
+
+// enforced whether the kernel stops the process from exceeding the limit
+func (m *memoryLimiter) enforced() bool {
+	return m.limit > 0
+}
+
+// nearLimit whether an allocation of the process exceeded RLIMIT_AS or its
+// address space was close to it, so it probably failed because of that
+func (m *memoryLimiter) nearLimit() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.child && m.limit > 0 && (m.allocFailed.Load() || m.vmPeak >= m.limit-m.limit/8)
+}
+
+// oomKilled whether the process was killed by the oom killer of the cgroup
+func (m *memoryLimiter) oomKilled() bool {
+	if m.cgroup == "" {
+		return false
+	}
+	b, err := os.ReadFile(filepath.Join(m.cgroup, "memory.events"))
+	if err != nil {
+		return false
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "oom_kill" && fields[1] != "0" {
+			return true
+		}
+	}
+	return false
+}
+
+// exact whether the cgroup reports the peak memory, otherwise it's sampled
+// while the process is running
+func (m *memoryLimiter) exact() bool {
+	return m.hasPeak
+}
+
+// sampling whether the peak memory has to be sampled by sample while the
+// process is running, since the cgroup doesn't report it
+func (m *memoryLimiter) sampling() bool {
+	return !m.hasPeak
+}
+
+// sample VmHWM and VmPeak of the running process, once it has executed the
+// program. Those of cf before exec, e.g. of the sandbox, are not counted
+func (m *memoryLimiter) sample(pid int) {
+	if exe, err := os.Readlink(fmt.Sprintf("/proc/%v/exe", pid)); err != nil || exe == m.self {
+		return
+	}
+	m.record(readStatus(pid))
+}
+
+// record the sampled status of the program
+func (m *memoryLimiter) record(status map[string]uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hwm = max(m.hwm, status["VmHWM:"])
+	m.vmPeak = max(m.vmPeak, status["VmPeak:"])
+}
+
+// peak memory of the process in bytes. exact is false when it's sampled, so
+// a fast program may be missed, and it's 0 if there is no sample
+func (m *memoryLimiter) peak() (peak uint64, exact bool) {
+	if m.hasPeak {
+		b, err := os.ReadFile(filepath.Join(m.cgroup, "memory.peak"))
+		if err == nil {
+			if peak, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64); err == nil {
+				return peak, true
+			}
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.hwm, false
+}
+
+// close stop watching the allocations, kill what is left in the cgroup and
+// remove it
+func (m *memoryLimiter) close() {
+	if m.conn != nil {
+		if m.stop != nil {
+			m.stop.Close()
+		}
+		m.watching.Wait()
+		if m.stopped != nil {
+			m.stopped.Close()
+		}
+		m.conn.Close()
+		if m.connChild != nil {
+			m.connChild.Close()
+		}
+	}
+	if m.cgroup == "" {
+		return
+	}
+	os.WriteFile(filepath.Join(m.cgroup, "cgroup.kill"), []byte("1"), 0644)
+	syscall.Close(m.fd)
+	os.Remove(m.cgroup)
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExecuteOneBigAllocation(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc is not found")
+	}
+	const mb = 1 << 20
+	tests := []struct {
+		name   string
+		source string
+		limit  uint64
+		oom    bool
+	}{
+		{"checked", `
+#include <stdlib.h>
+#include <string.h>
+int main() {
+	char *p = malloc(400 << 20);
+	if (!p) return 3;
+	memset(p, 1, 400 << 20);
+	return 0;
+}`, 256 * mb, true},
+		{"dereferenced", `
+#include <stdlib.h>
+#include <string.h>
+int main() {
+	char *p = malloc(100 << 20);
+	memset(p, 1, 100 << 20);
+	return 0;
+}`, 50 * mb, true},
+		{"brk", `
+#include <unistd.h>
+int main() {
+	if (sbrk(100 << 20) == (void *)-1) return 3;
+	return 0;
+}`, 50 * mb, true},
+		{"fits", `
+#include <stdlib.h>
+#include <string.h>
+int main() {
+	char *p = malloc(10 << 20);
+	if (!p) return 3;
+	memset(p, 1, 10 << 20);
+	return 0;
+}`, 256 * mb, false},
+	}
+	dir := t.TempDir()
+	for _, test := range tests {
+		path := filepath.Join(dir, test.name)
+		build := exec.Command("gcc", "-O0", "-o", path, "-x", "c", "-")
+		build.Stdin = strings.NewReader(test.source)
+		if out, err := build.CombinedOutput(); err != nil {
+			t.Fatalf("%v: %v\n%s", test.name, err, out)
+		}
+		e, err := execute(exec.Command(path), judgeLimit{Memory: test.limit}, io.Discard)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		if e.OOM != test.oom {
+			t.Errorf("%v: OOM = %v (err %v), want %v", test.name, e.OOM, e.Err, test.oom)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+//go:build !linux
+
+package cmd
+
+import (
+	"os/exec"
+	"time"
+)
+
+// memoryLimiter only linux is able to constrain the memory. On other systems
+// the limit is checked against the memory sampled while the process runs
+type memoryLimiter struct {
+	limit uint64
+}
+
+// newMemoryLimiter must be called before cmd starts
+func newMemoryLimiter(cmd *exec.Cmd, limit uint64) *memoryLimiter {
+	return &memoryLimiter{limit: limit}
+}
+
+func (m *memoryLimiter) childRlimit() uint64 {
+	return 0
+}
+
+func (m *memoryLimiter) wrap(cmd *exec.Cmd) {}
+
+func (m *memoryLimiter) started() {}
+
+func (m *memoryLimiter) helperTime() time.Duration {
+	return 0
+}
+
+func (m *memoryLimiter) enforced() bool {
+	return false
+}
+
+func (m *memoryLimiter) nearLimit() bool {
+	return false
+}
+
+func (m *memoryLimiter) oomKilled() bool {
+	return false
+}
+
+func (m *memoryLimiter) exact() bool {
+	return false
+}
+
+func (m *memoryLimiter) sampling() bool {
+	return false
+}
+
+func (m *memoryLimiter) sample(pid int) {}
+
+func (m *memoryLimiter) peak() (uint64, bool) {
+	return 0, false
+}
+
+func (m *memoryLimiter) close() {}
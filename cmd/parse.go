@@ -26,7 +26,7 @@ func Parse() (err error) {
 		}
 	}
 	work := func() error {
-		_, paths, err := cln.Parse(info)
+		_, paths, err := cln.Parse(info, Args.Statement)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"os/exec"
+
+	"golang.org/x/sys/unix"
+)
+
+// waitProcess waits for the process and kills what it left in its process
+// group. The group is killed before the process is reaped, otherwise the
+// process group id could have been reused by then
+func waitProcess(cmd *exec.Cmd) error {
+	var info unix.Siginfo
+	for {
+		err := unix.Waitid(unix.P_PID, cmd.Process.Pid, &info, unix.WEXITED|unix.WNOWAIT, nil)
+		if err != unix.EINTR {
+			break
+		}
+	}
+	killProcessTree(cmd)
+	return cmd.Wait()
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !windows
+
+package cmd
+
+import "os/exec"
+
+// waitProcess waits for the process and kills what it left in its process
+// group. There is no waiting without reaping here, so the process group id
+// may have been reused in rare cases
+func waitProcess(cmd *exec.Cmd) error {
+	err := cmd.Wait()
+	killProcessTree(cmd)
+	return err
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// setProcessGroup nothing to do on windows, taskkill walks the tree
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessTree kill the process and all its children
+func killProcessTree(cmd *exec.Cmd) error {
+	kill := exec.Command("taskkill", "/T", "/F", "/PID", fmt.Sprint(cmd.Process.Pid))
+	if err := kill.Run(); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
+
+// waitProcess waits for the process and kills what it left
+func waitProcess(cmd *exec.Cmd) error {
+	err := cmd.Wait()
+	killProcessTree(cmd)
+	return err
+}
@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Verdicts of a sample
+const (
+	verdictPassed            = "Passed"
+	verdictFailed            = "Failed"
+	verdictTimeLimit         = "Time Limit Exceeded"
+	verdictMemoryLimit       = "Memory Limit Exceeded"
+	verdictRuntimeError      = "Runtime Error"
+	verdictCheckerFailed     = "Checker Failed"
+	verdictInteractorFailed  = "Interactor Failed"
+	verdictSecurityViolation = "Security Violation"
+	verdictInvalidInput      = "Invalid Input"
+	verdictValidatorFailed   = "Validator Failed"
+)
+
+// sampleResult the result of judging a sample. Input, output and answer are
+// only kept when the sample failed. They are truncated in the text report
+// only, since the json report is read by tools which may need them in full
+type sampleResult struct {
+	ID      string `json:"id"`
+	Verdict string `json:"verdict"`
+	// Time cpu time in seconds
+	Time float64 `json:"time"`
+	// Memory peak memory in bytes, 0 if it's unknown
+	Memory     uint64 `json:"memory"`
+	Input      string `json:"input,omitempty"`
+	Output     string `json:"output,omitempty"`
+	Answer     string `json:"answer,omitempty"`
+	Diff       string `json:"diff,omitempty"`
+	Transcript string `json:"transcript,omitempty"`
+	// Message of the checker, the interactor or the error
+	Message string `json:"message,omitempty"`
+	// Case the inner test of a sample with Cases tests where the output
+	// differs first, 0 if it's unknown
+	Case  int `json:"case,omitempty"`
+	Cases int `json:"cases,omitempty"`
+
+	mismatch    *mismatch
+	caseInput   string
+	interactive bool
+	// checked whether Message is of the checker, otherwise of the comparison
+	checked bool
+}
+
+func (r *sampleResult) passed() bool {
+	return r.Verdict == verdictPassed
+}
+
+// measure records the time and memory of the execution
+func (r *sampleResult) measure(e *execution) {
+	r.Time = e.CPUTime.Seconds()
+	r.Memory = e.Memory
+}
+
+// setDiff of the output and the answer. The plain diff shows the lines
+// around the first mismatching line, where lines of the output are marked by
+// "-" and lines of the answer by "+"
+func (r *sampleResult) setDiff(output, answer string) {
+	r.Output, r.Answer = output, answer
+	if r.mismatch = findMismatch(output, answer); r.mismatch != nil {
+		r.Diff = r.mismatch.unified(uncolored)
+	}
+}
+
+// setTokenDiff is setDiff around the k-th token, where the output compared by
+// the epsilon differs from the answer
+func (r *sampleResult) setTokenDiff(output, answer string, k int) {
+	r.Output, r.Answer = output, answer
+	if r.mismatch = findTokenMismatch(output, answer, k); r.mismatch != nil {
+		r.Diff = r.mismatch.unified(uncolored)
+	}
+}
+
+// setInnerTest finds the inner test of a sample with multiple tests which the
+// first mismatching line belongs to. indexes are the inner tests of the lines
+// of the input, and answerIndexes those of the answer. Without answerIndexes,
+// lines of the output can only be matched with inner tests when each of them
+// outputs one line, otherwise the inner test is unknown. So is it when the
+// indexes don't match the sample, e.g. it has been changed
+func (r *sampleResult) setInnerTest(indexes, answerIndexes []int) {
+	if r.mismatch == nil || len(indexes) == 0 {
+		return
+	}
+	input := strings.Split(strings.TrimSuffix(r.Input, "\n"), "\n")
+	if len(input) != len(indexes) {
+		return
+	}
+	cases := 0
+	for _, index := range indexes {
+		cases = max(cases, index)
+	}
+	if cases == 0 || r.mismatch.Line >= len(r.mismatch.answer) {
+		return
+	}
+	testCase := r.mismatch.Line + 1
+	if answerIndexes != nil {
+		if len(r.mismatch.answer) != len(answerIndexes) {
+			return
+		}
+		testCase = answerIndexes[r.mismatch.Line]
+	} else if len(r.mismatch.answer) != cases {
+		return
+	}
+	if testCase < 1 || testCase > cases {
+		return
+	}
+	r.Case, r.Cases = testCase, cases
+	caseInput := []string{}
+	for i, line := range input {
+		if indexes[i] == r.Case {
+			caseInput = append(caseInput, line)
+		}
+	}
+	r.caseInput = strings.Join(caseInput, "\n")
+}
+
+// formatMemory in B, KB or MB, "-" if it's unknown
+func formatMemory(memory uint64) string {
+	if memory == 0 {
+		return "-"
+	}
+	if memory > 1024*1024 {
+		return fmt.Sprintf("%.3fMB", float64(memory)/1024.0/1024.0)
+	} else if memory > 1024 {
+		return fmt.Sprintf("%.3fKB", float64(memory)/1024.0)
+	}
+	return fmt.Sprintf("%vB", memory)
+}
+
+// summary the time and memory of the sample
+func (r *sampleResult) summary() string {
+	return fmt.Sprintf("%.3fs %v", r.Time, formatMemory(r.Memory))
+}
+
+// print the colored report of the sample
+func (r *sampleResult) print(w io.Writer) {
+	switch r.Verdict {
+	case verdictPassed:
+		state := color.New(color.FgGreen).Sprintf("Passed #%v", r.ID)
+		fmt.Fprintf(w, "%v ... %v\n", state, r.summary())
+		return
+	case verdictTimeLimit, verdictMemoryLimit:
+		state := color.New(color.FgYellow).Sprintf("%v #%v", r.Verdict, r.ID)
+		fmt.Fprintf(w, "%v ... %v\n", state, r.summary())
+		return
+	case verdictSecurityViolation:
+		state := color.New(color.FgRed).Sprintf("%v #%v", r.Verdict, r.ID)
+		fmt.Fprintf(w, "%v ... %v\n", state, r.summary())
+		return
+	case verdictFailed:
+		// It failed before its output was judged, e.g. a file is missing
+		if r.Input == "" && r.Output == "" && r.Answer == "" && r.Transcript == "" {
+			color.New(color.FgRed).Fprintf(w, "%v #%v ... %v\n", r.Verdict, r.ID, r.Message)
+			return
+		}
+	default:
+		color.New(color.FgRed).Fprintf(w, "%v #%v ... %v\n", r.Verdict, r.ID, r.Message)
+		return
+	}
+
+	// Long texts are truncated, otherwise large tests flood the terminal
+	section := func(title, text string) string {
+		return color.New(color.FgCyan).Sprintf("-----%v-----\n", title) + truncate(text) + "\n"
+	}
+	state := color.New(color.FgRed).Sprintf("Failed #%v", r.ID)
+	report := section("Input", r.Input)
+	if r.interactive {
+		report += section("Transcript", r.Transcript)
+		report += section("Interactor", r.Message)
+	} else if r.mismatch != nil {
+		diff := r.mismatch.unified(colored)
+		if Args.SideBySide {
+			diff = r.mismatch.sideBySide(colored)
+		}
+		report += color.New(color.FgCyan).Sprint("-----Diff-----\n") + diff + "\n"
+		if r.Case > 0 {
+			report += section(fmt.Sprintf("Test %v of %v", r.Case, r.Cases), r.caseInput)
+		}
+		if r.Message != "" {
+			report += r.Message + "\n"
+		}
+	} else {
+		report += section("Output", r.Output)
+		report += section("Answer", r.Answer)
+		if r.checked {
+			report += section("Checker", r.Message)
+		} else {
+			report += section("Compare", r.Message)
+		}
+	}
+	fmt.Fprintf(w, "%v ... %v\n%v", state, r.summary(), report)
+}
+
+// testReport the results of all samples of a code
+type testReport struct {
+	File    string          `json:"file"`
+	Passed  int             `json:"passed"`
+	Total   int             `json:"total"`
+	Samples []*sampleResult `json:"samples"`
+}
+
+func newTestReport(file string, results []*sampleResult) *testReport {
+	report := &testReport{File: file, Total: len(results), Samples: results}
+	for _, r := range results {
+		if r.passed() {
+			report.Passed++
+		}
+	}
+	return report
+}
+
+func (t *testReport) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+}
+
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+// writeJUnit writes the report as JUnit XML. Wrong answers and exceeded
+// limits are failures, the others are errors
+func (t *testReport) writeJUnit(w io.Writer) error {
+	suite := junitSuite{Name: t.File, Tests: t.Total}
+	total := 0.0
+	for _, r := range t.Samples {
+		total += r.Time
+		c := junitCase{
+			Name:      fmt.Sprintf("#%v", r.ID),
+			ClassName: t.File,
+			Time:      fmt.Sprintf("%.3f", r.Time),
+		}
+		switch r.Verdict {
+		case verdictPassed:
+		case verdictFailed, verdictTimeLimit, verdictMemoryLimit, verdictSecurityViolation:
+			text := r.Message
+			if r.Diff != "" {
+				text = r.Diff
+			} else if r.Transcript != "" {
+				text = r.Transcript + "\n" + r.Message
+			}
+			c.Failure = &junitProblem{Message: r.Verdict, Type: r.Verdict, Text: text}
+			suite.Failures++
+		default:
+			c.Error = &junitProblem{Message: r.Message, Type: r.Verdict}
+			suite.Errors++
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	suite.Time = fmt.Sprintf("%.3f", total)
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import "testing"
+
+func TestSetInnerTest(t *testing.T) {
+	tests := []struct {
+		name                  string
+		input, output, answer string
+		indexes               []int
+		answerIndexes         []int
+		testCase, cases       int
+		caseInput             string
+	}{
+		{"one line each", "3\n1\n2\n3\n", "1\n5\n3\n", "1\n2\n3\n", []int{0, 1, 2, 3}, nil, 2, 3, "2"},
+		{"multiline inputs", "2\n1 2\n3\n4\n", "YES\nNO\n", "YES\nYES\n", []int{0, 1, 1, 2}, nil, 2, 2, "4"},
+		{"first test", "2\n1\n2\n", "0\n2\n", "1\n2\n", []int{0, 1, 2}, nil, 1, 2, "1"},
+		{"several lines each", "2\n1\n2\n", "1\n1\n2\n3\n", "1\n1\n2\n2\n", []int{0, 1, 2}, nil, 0, 0, ""},
+		{"changed input", "2\n1\n2\n3\n", "1\n3\n", "1\n2\n", []int{0, 1, 2}, nil, 0, 0, ""},
+		{"no indexes", "2\n1\n2\n", "1\n3\n", "1\n2\n", nil, nil, 0, 0, ""},
+		{"extra output", "2\n1\n2\n", "1\n2\n3\n", "1\n2\n", []int{0, 1, 2}, nil, 0, 0, ""},
+		{"same", "2\n1\n2\n", "1\n2\n", "1\n2\n", []int{0, 1, 2}, nil, 0, 0, ""},
+		{"highlighted answer", "2\n1\n2\n", "1\n1\n2\n3\n", "1\n1\n2\n2\n", []int{0, 1, 2}, []int{1, 1, 2, 2}, 2, 2, "2"},
+		{"highlighted first test", "2\n1\n2\n", "1\n0\n2\n2\n", "1\n1\n2\n2\n", []int{0, 1, 2}, []int{1, 1, 2, 2}, 1, 2, "1"},
+		{"changed answer", "2\n1\n2\n", "1\n3\n", "1\n2\n", []int{0, 1, 2}, []int{1, 1, 2, 2}, 0, 0, ""},
+	}
+	for _, test := range tests {
+		r := &sampleResult{Input: test.input}
+		r.setDiff(test.output, test.answer)
+		r.setInnerTest(test.indexes, test.answerIndexes)
+		if r.Case != test.testCase || r.Cases != test.cases || r.caseInput != test.caseInput {
+			t.Errorf("%v: setInnerTest = test %v of %v with %q, want test %v of %v with %q",
+				test.name, r.Case, r.Cases, r.caseInput, test.testCase, test.cases, test.caseInput)
+		}
+	}
+}
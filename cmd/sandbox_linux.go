@@ -0,0 +1,239 @@
+//go:build linux && (amd64 || arm64)
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// sandboxName is argv[0] of cf when it's re-executed to set up the sandbox
+// for a program
+const sandboxName = "cf-sandbox"
+
+// sandboxFailure exit code when the sandbox cannot be set up
+const sandboxFailure = 125
+
+// commonForbidden syscalls killing the program in the sandbox. Creating
+// processes is forbidden as well, except threads
+var commonForbidden = []uintptr{
+	unix.SYS_SOCKET, unix.SYS_SOCKETPAIR, unix.SYS_CONNECT, unix.SYS_BIND,
+	unix.SYS_LISTEN, unix.SYS_ACCEPT, unix.SYS_ACCEPT4, unix.SYS_PTRACE,
+	unix.SYS_PROCESS_VM_READV, unix.SYS_PROCESS_VM_WRITEV, unix.SYS_MOUNT,
+	unix.SYS_UMOUNT2, unix.SYS_PIVOT_ROOT, unix.SYS_CHROOT, unix.SYS_SETNS,
+	unix.SYS_UNSHARE, unix.SYS_REBOOT, unix.SYS_KEXEC_LOAD,
+	unix.SYS_INIT_MODULE, unix.SYS_FINIT_MODULE, unix.SYS_DELETE_MODULE,
+	unix.SYS_BPF, unix.SYS_PERF_EVENT_OPEN, unix.SYS_KEYCTL, unix.SYS_ADD_KEY,
+	unix.SYS_REQUEST_KEY, unix.SYS_SWAPON, unix.SYS_SWAPOFF,
+	// The new mount API, which could remount the file system writable
+	unix.SYS_OPEN_TREE, unix.SYS_OPEN_TREE_ATTR, unix.SYS_MOVE_MOUNT,
+	unix.SYS_FSOPEN, unix.SYS_FSCONFIG, unix.SYS_FSMOUNT, unix.SYS_FSPICK,
+	unix.SYS_MOUNT_SETATTR,
+}
+
+func init() {
+	if len(os.Args) < 5 || os.Args[0] != sandboxName {
+		return
+	}
+	// The seccomp filter is installed for the current thread, which must be
+	// the one calling exec
+	runtime.LockOSThread()
+	err := enterSandbox(os.Args[1], os.Args[2], os.Args[3], os.Args[4:])
+	fmt.Fprintf(os.Stderr, "cf sandbox: %v\n", err)
+	os.Exit(sandboxFailure)
+}
+
+// sandbox makes cmd run in the sandbox. cf is re-executed in new user, mount,
+// pid, network, ipc and uts namespaces, where it makes the file system read
+// only, installs the seccomp filter and then executes the program. memory is
+// RLIMIT_AS of the program, 0 means no limit. cmd.Dir stays writable if it's
+// set, e.g. the temporary folder of a problem with file IO
+func sandbox(cmd *exec.Cmd, memory uint64) error {
+	if cmd.Err != nil {
+		return cmd.Err
+	}
+	writable := ""
+	if cmd.Dir != "" {
+		dir, err := filepath.Abs(cmd.Dir)
+		if err != nil {
+			return err
+		}
+		if writable, err = filepath.EvalSymlinks(dir); err != nil {
+			return err
+		}
+	}
+	cmd.Args = append([]string{sandboxName, fmt.Sprint(memory), writable, cmd.Path}, cmd.Args...)
+	cmd.Path = "/proc/self/exe"
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	attr := cmd.SysProcAttr
+	attr.Cloneflags |= syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+		syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+	attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+	attr.GidMappingsEnableSetgroups = false
+	return nil
+}
+
+// securityViolation whether the program was killed by the seccomp filter
+func securityViolation(state *os.ProcessState) bool {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGSYS
+}
+
+func enterSandbox(memory, writable, path string, args []string) error {
+	limit, err := strconv.ParseUint(memory, 10, 64)
+	if err != nil {
+		return err
+	}
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return err
+	}
+	// A bind mount of the folder on itself is a mount point of its own, which
+	// is skipped when the others are remounted read only. The working folder
+	// is entered again, otherwise it's still the one under the bind mount
+	if writable != "" {
+		if err := unix.Mount(writable, writable, "", unix.MS_BIND, ""); err != nil {
+			return err
+		}
+		if err := unix.Chdir(writable); err != nil {
+			return err
+		}
+	}
+	if err := remountReadOnly(writable); err != nil {
+		return err
+	}
+	// /dev/shm is writable by everyone, so the program gets an empty one of
+	// its own, as large as the memory limit
+	options := ""
+	if limit > 0 {
+		options = fmt.Sprintf("size=%v", limit)
+	}
+	err = unix.Mount("tmpfs", "/dev/shm", "tmpfs", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, options)
+	if err != nil && err != unix.ENOENT {
+		return fmt.Errorf("cannot mount /dev/shm: %v", err)
+	}
+	if err := unix.Mount("proc", "/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return err
+	}
+	if err := installSeccomp(); err != nil {
+		return err
+	}
+	return execProgram(limit, path, args)
+}
+
+// mountFlags which must be kept when remounting
+var mountFlags = map[string]uintptr{
+	"nosuid":      unix.MS_NOSUID,
+	"nodev":       unix.MS_NODEV,
+	"noexec":      unix.MS_NOEXEC,
+	"noatime":     unix.MS_NOATIME,
+	"nodiratime":  unix.MS_NODIRATIME,
+	"relatime":    unix.MS_RELATIME,
+	"strictatime": unix.MS_STRICTATIME,
+}
+
+// remountReadOnly remounts every mount point read only except writable.
+// Pseudo file systems which refuse it are skipped since /proc and /dev/shm
+// are replaced and the others are not writable by the user anyway
+func remountReadOnly(writable string) error {
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		target, err := strconv.Unquote(`"` + fields[4] + `"`)
+		if err != nil {
+			target = fields[4]
+		}
+		if target == writable {
+			continue
+		}
+		flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY)
+		for _, option := range strings.Split(fields[5], ",") {
+			flags |= mountFlags[option]
+		}
+		err = unix.Mount("", target, "", flags, "")
+		if err != nil && target == "/" {
+			return err
+		}
+		if err != nil && !strings.HasPrefix(target, "/proc") && !strings.HasPrefix(target, "/sys") &&
+			!strings.HasPrefix(target, "/dev") {
+			return fmt.Errorf("cannot remount %v read only: %v", target, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// installSeccomp kills the process on forbidden syscalls
+func installSeccomp() error {
+	stmt := func(code uint16, k uint32) unix.SockFilter {
+		return unix.SockFilter{Code: code, K: k}
+	}
+	jump := func(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+		return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+	}
+	const (
+		load  = unix.BPF_LD | unix.BPF_W | unix.BPF_ABS
+		jeq   = unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K
+		jge   = unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K
+		jset  = unix.BPF_JMP | unix.BPF_JSET | unix.BPF_K
+		ret   = unix.BPF_RET | unix.BPF_K
+		allow = unix.SECCOMP_RET_ALLOW
+		kill  = unix.SECCOMP_RET_KILL_PROCESS
+		// offsets in struct seccomp_data
+		nrOffset   = 0
+		archOffset = 4
+		argOffset  = 16
+	)
+	filter := []unix.SockFilter{
+		stmt(load, archOffset),
+		jump(jeq, auditArch, 1, 0),
+		stmt(ret, kill),
+		stmt(load, nrOffset),
+	}
+	// Otherwise the numbers with the x32 bit would bypass the filter
+	if x32SyscallBit != 0 {
+		filter = append(filter, jump(jge, x32SyscallBit, 0, 1), stmt(ret, kill))
+	}
+	for _, nr := range append(commonForbidden, archForbidden...) {
+		filter = append(filter, jump(jeq, uint32(nr), 0, 1), stmt(ret, kill))
+	}
+	// clone3 hides its flags in memory, so the libc falls back to clone
+	filter = append(filter,
+		jump(jeq, unix.SYS_CLONE3, 0, 1),
+		stmt(ret, unix.SECCOMP_RET_ERRNO|uint32(unix.ENOSYS)),
+		jump(jeq, unix.SYS_CLONE, 0, 2),
+		stmt(load, argOffset),
+		jump(jset, unix.CLONE_THREAD, 0, 1),
+		stmt(ret, allow),
+		stmt(ret, kill),
+	)
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return err
+	}
+	_, _, errno := unix.Syscall(unix.SYS_SECCOMP, unix.SECCOMP_SET_MODE_FILTER, 0, uintptr(unsafe.Pointer(&prog)))
+	if errno != 0 {
+		return errors.New("cannot install the seccomp filter: " + errno.Error())
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build !linux || !(amd64 || arm64)
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+func sandbox(cmd *exec.Cmd, memory uint64) error {
+	return errors.New("The sandbox is only supported on Linux on amd64 and arm64")
+}
+
+func securityViolation(state *os.ProcessState) bool {
+	return false
+}
@@ -0,0 +1,11 @@
+package cmd
+
+import "golang.org/x/sys/unix"
+
+const auditArch = unix.AUDIT_ARCH_X86_64
+
+// x32SyscallBit is set in the numbers of x32 syscalls, which share the arch
+const x32SyscallBit = 0x40000000
+
+// archForbidden syscalls only on this architecture, see commonForbidden
+var archForbidden = []uintptr{unix.SYS_FORK, unix.SYS_VFORK, unix.SYS_IOPL, unix.SYS_IOPERM}
@@ -0,0 +1,11 @@
+package cmd
+
+import "golang.org/x/sys/unix"
+
+const auditArch = unix.AUDIT_ARCH_AARCH64
+
+// x32SyscallBit there is no x32 ABI on arm64
+const x32SyscallBit = 0
+
+// archForbidden syscalls only on this architecture, see commonForbidden
+var archForbidden = []uintptr{}
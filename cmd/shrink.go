@@ -0,0 +1,418 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/NetWilliam/cf-tool/config"
+	"github.com/fatih/color"
+)
+
+// formatItem "x" is a token, "x[n]" is n tokens
+type formatItem struct {
+	name  string
+	count string
+}
+
+// formatLine a line of the input format. The line is repeated repeat times
+// if repeat is not empty
+type formatLine struct {
+	items  []formatItem
+	repeat string
+}
+
+// parseFormat parses the format hint. Lines are separated by ";", e.g.
+// "n m; a[n]; u v *m"
+func parseFormat(hint string) (format []formatLine, err error) {
+	itemReg := regexp.MustCompile(`^(\w+)(?:\[(\w+)\])?$`)
+	repeatReg := regexp.MustCompile(`^\*(\w+)$`)
+	for _, text := range strings.Split(hint, ";") {
+		line := formatLine{}
+		fields := strings.Fields(text)
+		for i, field := range fields {
+			if tmp := repeatReg.FindStringSubmatch(field); tmp != nil && i == len(fields)-1 {
+				line.repeat = tmp[1]
+			} else if tmp := itemReg.FindStringSubmatch(field); tmp != nil {
+				line.items = append(line.items, formatItem{tmp[1], tmp[2]})
+			} else {
+				return nil, fmt.Errorf("Invalid item %v in hint", field)
+			}
+		}
+		if len(line.items) > 0 {
+			format = append(format, line)
+		}
+	}
+	if len(format) == 0 {
+		return nil, errors.New("Empty hint")
+	}
+	return
+}
+
+// shapedLine a line of the input parsed by its format
+type shapedLine struct {
+	format *formatLine
+	// row index of the line in repeated lines
+	row    int
+	tokens [][]string
+}
+
+// shapedInput an input parsed by a format. Counts are the current values of
+// the variables which are the sizes of something
+type shapedInput struct {
+	lines  []shapedLine
+	counts map[string]int
+}
+
+func shapeInput(input []byte, format []formatLine) (*shapedInput, error) {
+	s := &shapedInput{counts: map[string]int{}}
+	values := map[string]string{}
+	resolve := func(name string) (int, error) {
+		value, ok := values[name]
+		if !ok {
+			value = name
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%v is not a valid size", name)
+		}
+		if ok {
+			s.counts[name] = n
+		}
+		return n, nil
+	}
+
+	lines := strings.Split(strings.TrimRight(string(input), "\r\n"), "\n")
+	next := 0
+	for i := range format {
+		f := &format[i]
+		rows, row := 1, -1
+		if f.repeat != "" {
+			n, err := resolve(f.repeat)
+			if err != nil {
+				return nil, err
+			}
+			rows, row = n, 0
+		}
+		for ; rows > 0; rows-- {
+			if next >= len(lines) {
+				return nil, errors.New("The input has fewer lines than the hint")
+			}
+			fields := strings.Fields(lines[next])
+			line := shapedLine{format: f, row: row}
+			for _, item := range f.items {
+				n := 1
+				if item.count != "" {
+					var err error
+					if n, err = resolve(item.count); err != nil {
+						return nil, err
+					}
+				}
+				if n > len(fields) {
+					return nil, fmt.Errorf("Line %v of the input doesn't match the hint", next+1)
+				}
+				if item.count == "" {
+					values[item.name] = fields[0]
+				}
+				line.tokens = append(line.tokens, fields[:n])
+				fields = fields[n:]
+			}
+			if len(fields) > 0 {
+				return nil, fmt.Errorf("Line %v of the input doesn't match the hint", next+1)
+			}
+			s.lines = append(s.lines, line)
+			next++
+			if row >= 0 {
+				row++
+			}
+		}
+	}
+	return s, nil
+}
+
+// keep returns a copy only having the elements of the variable name whose
+// indexes are in keep
+func (s *shapedInput) keep(name string, keep []int) *shapedInput {
+	index := make(map[int]int)
+	for i, k := range keep {
+		index[k] = i
+	}
+	t := &shapedInput{counts: map[string]int{}}
+	for k, v := range s.counts {
+		t.counts[k] = v
+	}
+	t.counts[name] = len(keep)
+	for _, line := range s.lines {
+		if line.format.repeat == name {
+			row, ok := index[line.row]
+			if !ok {
+				continue
+			}
+			line.row = row
+		}
+		tokens := make([][]string, len(line.tokens))
+		for i, item := range line.format.items {
+			if item.count != name {
+				tokens[i] = line.tokens[i]
+				continue
+			}
+			for _, k := range keep {
+				tokens[i] = append(tokens[i], line.tokens[i][k])
+			}
+		}
+		line.tokens = tokens
+		t.lines = append(t.lines, line)
+	}
+	return t
+}
+
+func (s *shapedInput) bytes() []byte {
+	var b bytes.Buffer
+	for _, line := range s.lines {
+		tokens := []string{}
+		for i, item := range line.format.items {
+			if n, ok := s.counts[item.name]; ok && item.count == "" {
+				tokens = append(tokens, fmt.Sprint(n))
+			} else {
+				tokens = append(tokens, line.tokens[i]...)
+			}
+		}
+		b.WriteString(strings.Join(tokens, " "))
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// shrinkList is delta debugging on n units. It removes chunks of units while
+// fails still returns true on the units left, and returns the indexes of them
+func shrinkList(n int, fails func(keep []int) (bool, error)) ([]int, error) {
+	keep := make([]int, n)
+	for i := range keep {
+		keep[i] = i
+	}
+	chunk := (n + 1) / 2
+	for chunk >= 1 && len(keep) > 0 {
+		removed := false
+		for start := 0; start < len(keep); {
+			end := start + chunk
+			if end > len(keep) {
+				end = len(keep)
+			}
+			candidate := append(append([]int{}, keep[:start]...), keep[end:]...)
+			failed, err := fails(candidate)
+			if err != nil {
+				return nil, err
+			}
+			if failed {
+				keep = candidate
+				removed = true
+			} else {
+				start = end
+			}
+		}
+		if !removed {
+			chunk /= 2
+		} else if chunk > (len(keep)+1)/2 {
+			chunk = (len(keep) + 1) / 2
+		}
+	}
+	return keep, nil
+}
+
+// shrinker shrinks an input while the code still fails on it
+type shrinker struct {
+	d      *differ
+	input  []byte
+	answer []byte
+}
+
+// try whether the code fails on the input, and remembers it if it's smaller
+func (s *shrinker) try(input []byte) (bool, error) {
+	failed, answer, err := s.d.fails(input)
+	if errors.Is(err, errInvalidInput) || errors.Is(err, errTrustedFailed) {
+		// The validator or the trusted solution rejects it, so it's not a
+		// valid input
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if failed && len(input) <= len(s.input) {
+		s.input, s.answer = input, answer
+		fmt.Printf("\rShrunk to %v bytes    ", len(input))
+	}
+	return failed, nil
+}
+
+// byLines removes lines, then tokens of each line
+func (s *shrinker) byLines() error {
+	lines := strings.Split(strings.TrimRight(string(s.input), "\r\n"), "\n")
+	join := func(lines []string, keep []int) []byte {
+		var b bytes.Buffer
+		for _, k := range keep {
+			b.WriteString(lines[k])
+			b.WriteByte('\n')
+		}
+		return b.Bytes()
+	}
+	keep, err := shrinkList(len(lines), func(keep []int) (bool, error) {
+		return s.try(join(lines, keep))
+	})
+	if err != nil {
+		return err
+	}
+	kept := []string{}
+	for _, k := range keep {
+		kept = append(kept, lines[k])
+	}
+	lines = kept
+	all := make([]int, len(lines))
+	for i := range all {
+		all[i] = i
+	}
+
+	for i := range lines {
+		tokens := strings.Fields(lines[i])
+		line := func(keep []int) string {
+			kept := []string{}
+			for _, k := range keep {
+				kept = append(kept, tokens[k])
+			}
+			return strings.Join(kept, " ")
+		}
+		keep, err := shrinkList(len(tokens), func(keep []int) (bool, error) {
+			origin := lines[i]
+			lines[i] = line(keep)
+			defer func() { lines[i] = origin }()
+			return s.try(join(lines, all))
+		})
+		if err != nil {
+			return err
+		}
+		lines[i] = line(keep)
+	}
+	return nil
+}
+
+// byFormat removes elements of arrays and repeated lines, and fixes their
+// sizes according to the format. byLines may shrink the result further
+func (s *shrinker) byFormat(format []formatLine) error {
+	shaped, err := shapeInput(s.input, format)
+	if err != nil {
+		return err
+	}
+	names := []string{}
+	for _, line := range format {
+		for _, item := range line.items {
+			if _, ok := shaped.counts[item.name]; ok && item.count == "" {
+				names = append(names, item.name)
+			}
+		}
+	}
+	for _, name := range names {
+		keep, err := shrinkList(shaped.counts[name], func(keep []int) (bool, error) {
+			return s.try(shaped.keep(name, keep).bytes())
+		})
+		if err != nil {
+			return err
+		}
+		shaped = shaped.keep(name, keep)
+	}
+	return nil
+}
+
+// Shrink command
+func Shrink() (err error) {
+	cfg := config.Instance
+	if len(cfg.Template) == 0 {
+		return errors.New("You have to add at least one code template by `cf config`")
+	}
+	inPath := Args.Input
+	if _, err := os.Stat(inPath); err != nil {
+		if _, e := os.Stat(fmt.Sprintf("in%v.txt", inPath)); e != nil {
+			return err
+		}
+		inPath = fmt.Sprintf("in%v.txt", inPath)
+	}
+	input, err := os.ReadFile(inPath)
+	if err != nil {
+		return
+	}
+	var format []formatLine
+	if Args.Hint != "" {
+		if format, err = parseFormat(Args.Hint); err != nil {
+			return
+		}
+	}
+	bruteFile := Args.Brute
+	if bruteFile == "" {
+		if bruteFile = findCodeByName("brute", cfg.Template); bruteFile == "" {
+			return errors.New(`Cannot find the trusted solution. Create "brute.<suffix>" or specify it by -b`)
+		}
+	}
+	filename, index, err := getOneSolution(Args.File, cfg.Template)
+	if err != nil {
+		return
+	}
+	conf, err := newJudgeConfig(cfg.Template[index])
+	if err != nil {
+		return
+	}
+	if conf.Interactor != "" {
+		return errors.New("cf shrink doesn't support interactive problems")
+	}
+	if err = checkStandardIO("cf shrink", conf.InputFile, conf.OutputFile); err != nil {
+		return
+	}
+	brute, bruteCommand, err := prepareHelper(bruteFile, cfg.Template)
+	if err != nil {
+		return
+	}
+	defer cleanUp(brute, &err)
+	scripts, command, err := prepareCode(filename, cfg.Template[index])
+	if err != nil {
+		return
+	}
+	defer cleanUp(scripts, &err)
+	conf.Command, conf.Sources = command, scripts.sources()
+	d, err := newDiffer(bruteCommand, conf)
+	if err != nil {
+		return
+	}
+	defer d.close()
+
+	s := &shrinker{d: d, input: input}
+	failed, answer, err := d.fails(input)
+	if err != nil {
+		return
+	}
+	if !failed {
+		return fmt.Errorf("%v doesn't make the outputs differ", inPath)
+	}
+	s.answer = answer
+	if format != nil {
+		err = s.byFormat(format)
+	}
+	if err == nil {
+		err = s.byLines()
+	}
+	fmt.Println()
+	if err != nil {
+		return
+	}
+
+	sampleID, err := saveSample(s.input, s.answer)
+	if err != nil {
+		return
+	}
+	color.Green("Shrunk %v from %v bytes to %v bytes. Saved as in%v.txt and ans%v.txt",
+		inPath, len(input), len(s.input), sampleID, sampleID)
+	if err = judge(sampleID, conf, color.Output, os.Stderr); err != nil {
+		color.Red(err.Error())
+	}
+	return nil
+}
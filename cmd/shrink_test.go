@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		hint   string
+		format []formatLine
+		err    bool
+	}{
+		{"n; a[n]", []formatLine{
+			{items: []formatItem{{"n", ""}}},
+			{items: []formatItem{{"a", "n"}}},
+		}, false},
+		{"n m; u v *m", []formatLine{
+			{items: []formatItem{{"n", ""}, {"m", ""}}},
+			{items: []formatItem{{"u", ""}, {"v", ""}}, repeat: "m"},
+		}, false},
+		{" n q ;; a[n] ; l r *q ", []formatLine{
+			{items: []formatItem{{"n", ""}, {"q", ""}}},
+			{items: []formatItem{{"a", "n"}}},
+			{items: []formatItem{{"l", ""}, {"r", ""}}, repeat: "q"},
+		}, false},
+		{"a[3]", []formatLine{{items: []formatItem{{"a", "3"}}}}, false},
+		{"", nil, true},
+		{" ; ", nil, true},
+		{"n; a[n", nil, true},
+		{"*m u v", nil, true},
+	}
+	for _, test := range tests {
+		format, err := parseFormat(test.hint)
+		if (err != nil) != test.err {
+			t.Errorf("parseFormat(%q) error = %v, want error %v", test.hint, err, test.err)
+			continue
+		}
+		if !reflect.DeepEqual(format, test.format) {
+			t.Errorf("parseFormat(%q) = %+v, want %+v", test.hint, format, test.format)
+		}
+	}
+}
+
+func TestShrinkList(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		// needed the units which must be kept to fail
+		needed []int
+		keep   []int
+	}{
+		{"empty", 0, nil, []int{}},
+		{"one removable", 1, nil, []int{}},
+		{"one needed", 1, []int{0}, []int{0}},
+		{"all removable", 8, nil, []int{}},
+		{"one of many", 10, []int{7}, []int{7}},
+		{"two apart", 9, []int{0, 8}, []int{0, 8}},
+		{"all needed", 5, []int{0, 1, 2, 3, 4}, []int{0, 1, 2, 3, 4}},
+	}
+	for _, test := range tests {
+		keep, err := shrinkList(test.n, func(keep []int) (bool, error) {
+			has := map[int]bool{}
+			for _, k := range keep {
+				has[k] = true
+			}
+			for _, k := range test.needed {
+				if !has[k] {
+					return false, nil
+				}
+			}
+			return true, nil
+		})
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(keep, test.keep) {
+			t.Errorf("%v: shrinkList(%v) = %v, want %v", test.name, test.n, keep, test.keep)
+		}
+	}
+}
+
+func TestShrinkListError(t *testing.T) {
+	_, err := shrinkList(4, func(keep []int) (bool, error) {
+		return false, errInterrupted
+	})
+	if err != errInterrupted {
+		t.Errorf("shrinkList error = %v, want %v", err, errInterrupted)
+	}
+}
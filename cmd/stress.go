@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/NetWilliam/cf-tool/config"
+	"github.com/fatih/color"
+)
+
+// runCode runs command with the input and returns its standard output. It
+// doesn't support file IO, see checkStandardIO
+func runCode(command string, args []string, input []byte, limit judgeLimit, stderr io.Writer) ([]byte, *execution, error) {
+	var o bytes.Buffer
+	cmds := splitCmd(command)
+	cmd := exec.Command(cmds[0], append(cmds[1:], args...)...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &o
+	e, err := execute(cmd, limit, stderr)
+	return o.Bytes(), e, err
+}
+
+const (
+	// helperTimeFactor the helpers may run so many times longer than the
+	// time limit of the solution
+	helperTimeFactor = 10
+	// helperTimeLimit the least time limit of them
+	helperTimeLimit = 10 * time.Second
+)
+
+// runHelper runs a generator, a trusted solution, a checker or a validator
+// like runCode. They may be much slower than the solution of limit, but they
+// must not hang
+func runHelper(command string, args []string, input []byte, limit judgeLimit, stderr io.Writer) ([]byte, *execution, error) {
+	limit = judgeLimit{Time: max(helperTimeFactor*limit.Time, helperTimeLimit)}
+	output, e, err := runCode(command, args, input, limit, stderr)
+	if err == nil && e.Killed {
+		e.Err = fmt.Errorf("Time limit of %v exceeded", limit.Time)
+	}
+	return output, e, err
+}
+
+// Errors of differ.fails when the input is not a valid one
+var (
+	errInvalidInput  = errors.New("Invalid input")
+	errTrustedFailed = errors.New("Trusted solution failed")
+)
+
+// differ compares the code with a trusted solution on any input
+type differ struct {
+	brute string
+	conf  judgeConfig
+	dir   string
+}
+
+func newDiffer(brute string, conf judgeConfig) (*differ, error) {
+	dir, err := os.MkdirTemp("", "cf-stress-")
+	if err != nil {
+		return nil, err
+	}
+	return &differ{brute, conf, dir}, nil
+}
+
+// fails whether the code fails on the input. The answer is the output of the
+// trusted solution, which must not fail. The input must be accepted by the
+// validator if there is one
+func (d *differ) fails(input []byte) (failed bool, answer []byte, err error) {
+	if d.conf.Validator != "" {
+		valid, message, err := validate(d.conf.Validator, input, d.conf.Limit)
+		if err != nil {
+			return false, nil, fmt.Errorf("Validator failed: %v", err.Error())
+		}
+		if !valid {
+			return false, nil, fmt.Errorf("%w: %v", errInvalidInput, message)
+		}
+	}
+	answer, e, err := runHelper(d.brute, nil, input, d.conf.Limit, io.Discard)
+	if err != nil {
+		return
+	}
+	if e.Err != nil {
+		return false, nil, fmt.Errorf("%w: %v", errTrustedFailed, e.Err.Error())
+	}
+	inPath := filepath.Join(d.dir, "in.txt")
+	ansPath := filepath.Join(d.dir, "ans.txt")
+	if err = os.WriteFile(inPath, input, 0644); err != nil {
+		return
+	}
+	if err = os.WriteFile(ansPath, answer, 0644); err != nil {
+		return
+	}
+	output, e, err := runCode(d.conf.Command, nil, input, d.conf.Limit, io.Discard)
+	if err != nil {
+		return
+	}
+	if e.verdict() != "" || e.Err != nil {
+		return true, answer, nil
+	}
+	ok, _, err := verify(d.conf, inPath, ansPath, output)
+	return !ok, answer, err
+}
+
+func (d *differ) close() {
+	os.RemoveAll(d.dir)
+}
+
+// saveSample saves the input and the answer as a new sample
+func saveSample(input, answer []byte) (string, error) {
+	sampleID := nextSampleID()
+	if err := dropSampleTests(sampleID); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(inputPath(sampleID), input, 0644); err != nil {
+		return "", err
+	}
+	return sampleID, os.WriteFile(answerPath(sampleID), answer, 0644)
+}
+
+// prepareCode runs before_script of the code with the template and returns
+// the scripts of it
+func prepareCode(filename string, template config.CodeTemplate) (*codeScripts, string, error) {
+	scripts := newCodeScripts(filename, template)
+	if err := scripts.before(); err != nil {
+		return nil, "", err
+	}
+	command, err := scripts.command()
+	return scripts, command, err
+}
+
+// prepareHelper is prepareCode for a generator or a trusted solution, whose
+// template is chosen by its suffix
+func prepareHelper(filename string, templates []config.CodeTemplate) (*codeScripts, string, error) {
+	filename, index, err := getOneCode(filename, templates)
+	if err != nil {
+		return nil, "", err
+	}
+	return prepareCode(filename, templates[index])
+}
+
+// cleanUp runs after_script of the code. It's deferred, so it runs when the
+// command fails too. err is set to its error unless there is one already
+func cleanUp(scripts *codeScripts, err *error) {
+	if e := scripts.after(); *err == nil {
+		*err = e
+	}
+}
+
+// Stress command
+func Stress() (err error) {
+	cfg := config.Instance
+	if len(cfg.Template) == 0 {
+		return errors.New("You have to add at least one code template by `cf config`")
+	}
+	count := 0
+	if Args.Count != "" {
+		if count, err = strconv.Atoi(Args.Count); err != nil || count < 1 {
+			return fmt.Errorf("Invalid count %v", Args.Count)
+		}
+	}
+	genFile := Args.Generator
+	if genFile == "" {
+		if genFile = findCodeByName("gen", cfg.Template); genFile == "" {
+			return errors.New(`Cannot find the generator. Create "gen.<suffix>" or specify it by -g`)
+		}
+	}
+	bruteFile := Args.Brute
+	if bruteFile == "" {
+		if bruteFile = findCodeByName("brute", cfg.Template); bruteFile == "" {
+			return errors.New(`Cannot find the trusted solution. Create "brute.<suffix>" or specify it by -b`)
+		}
+	}
+	filename, index, err := getOneSolution(Args.File, cfg.Template)
+	if err != nil {
+		return
+	}
+	conf, err := newJudgeConfig(cfg.Template[index])
+	if err != nil {
+		return
+	}
+	if conf.Interactor != "" {
+		return errors.New("cf stress doesn't support interactive problems")
+	}
+	if err = checkStandardIO("cf stress", conf.InputFile, conf.OutputFile); err != nil {
+		return
+	}
+
+	gen, genCommand, err := prepareHelper(genFile, cfg.Template)
+	if err != nil {
+		return
+	}
+	defer cleanUp(gen, &err)
+	brute, bruteCommand, err := prepareHelper(bruteFile, cfg.Template)
+	if err != nil {
+		return
+	}
+	defer cleanUp(brute, &err)
+	scripts, command, err := prepareCode(filename, cfg.Template[index])
+	if err != nil {
+		return
+	}
+	defer cleanUp(scripts, &err)
+	conf.Command, conf.Sources = command, scripts.sources()
+
+	d, err := newDiffer(bruteCommand, conf)
+	if err != nil {
+		return
+	}
+	defer d.close()
+
+	found := false
+	for i := 1; count == 0 || i <= count; i++ {
+		seed := rand.Int63()
+		input, e, err := runHelper(genCommand, []string{fmt.Sprint(seed)}, nil, conf.Limit, os.Stderr)
+		if err != nil {
+			return err
+		}
+		if e.Err != nil {
+			return fmt.Errorf("Generator failed with seed %v: %v", seed, e.Err.Error())
+		}
+		failed, answer, err := d.fails(input)
+		if err != nil {
+			return fmt.Errorf("%v with seed %v", err.Error(), seed)
+		}
+		if !failed {
+			fmt.Printf("\rPassed %v tests", i)
+			continue
+		}
+
+		fmt.Println()
+		sampleID, err := saveSample(input, answer)
+		if err != nil {
+			return err
+		}
+		color.Red("Found a counterexample in test %v with seed %v. Saved as in%v.txt and ans%v.txt",
+			i, seed, sampleID, sampleID)
+		if err = judge(sampleID, conf, color.Output, os.Stderr); err != nil {
+			color.Red(err.Error())
+		}
+		found = true
+		break
+	}
+	if !found {
+		fmt.Println()
+		color.Green("No counterexample is found")
+	}
+	return
+}
@@ -8,15 +8,20 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 
+	"github.com/NetWilliam/cf-tool/client"
 	"github.com/NetWilliam/cf-tool/config"
 	"github.com/NetWilliam/cf-tool/util"
 	"github.com/fatih/color"
 	ansi "github.com/k0kubun/go-ansi"
-	"github.com/sergi/go-diff/diffmatchpatch"
 	"github.com/shirou/gopsutil/process"
 )
 
@@ -60,145 +65,726 @@ func plain(raw []byte) string {
 	return b.String()
 }
 
-func judge(sampleID, command string) error {
-	inPath := fmt.Sprintf("in%v.txt", sampleID)
-	ansPath := fmt.Sprintf("ans%v.txt", sampleID)
-	input, err := os.Open(inPath)
-	if err != nil {
-		return err
+var errInterrupted = errors.New("Interrupted")
+
+// allocFailureReg messages of common runtimes when an allocation failed
+var allocFailureReg = regexp.MustCompile(`bad_alloc|MemoryError|OutOfMemoryError|[Cc]annot allocate memory|[Oo]ut of memory`)
+
+// judgeLimit limits of running a sample. Zero value means no limit
+type judgeLimit struct {
+	Time   time.Duration
+	Memory uint64
+	// Sandbox run the program in the sandbox
+	Sandbox bool
+}
+
+// judgeConfig how to judge a sample
+type judgeConfig struct {
+	Command string
+	// Sources the arguments of Command which are the code, see
+	// codeScripts.sources
+	Sources []string
+	Limit   judgeLimit
+	// Checker command of a testlib style checker, empty means comparing the
+	// output with the answer
+	Checker string
+	// Epsilon compare real numbers with this absolute or relative error when
+	// it's positive
+	Epsilon float64
+	// Interactor command of a testlib style interactor, non-empty means the
+	// problem is interactive
+	Interactor string
+	// Validator command of a testlib style validator checking inputs, empty
+	// means inputs are not checked
+	Validator string
+	// Tests the inner tests of the samples with multiple tests, and
+	// AnswerTests of their answers, see client.Problem.SampleTests
+	Tests       map[string][]int
+	AnswerTests map[string][]int
+	// InputFile and OutputFile are the files which the program reads and
+	// writes instead of the standard input and output, if they are not empty
+	InputFile  string
+	OutputFile string
+}
+
+// tailWriter remembers the last max bytes written to it
+type tailWriter struct {
+	buf []byte
+	max int
+}
+
+func (t *tailWriter) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.max {
+		t.buf = t.buf[len(t.buf)-t.max:]
 	}
-	var o bytes.Buffer
-	output := io.Writer(&o)
+	return len(p), nil
+}
 
-	cmds := splitCmd(command)
+// execution how a program ran
+type execution struct {
+	Err     error
+	Killed  bool
+	CPUTime time.Duration
+	Memory  uint64
+	// OOM whether the process hit the memory limit
+	OOM bool
+	// Violation whether the process was killed by the sandbox
+	Violation bool
+	// Stopped whether the process was killed by the stop of executeUntil
+	Stopped bool
+}
 
-	cmd := exec.Command(cmds[0], cmds[1:]...)
-	cmd.Stdin = input
-	cmd.Stdout = output
-	cmd.Stderr = os.Stderr
+// execute cmd with the limit and wait for it. The standard error of cmd is
+// written to stderr. An error is returned only if cmd cannot start or it is
+// interrupted
+func execute(cmd *exec.Cmd, limit judgeLimit, stderr io.Writer) (*execution, error) {
+	return executeUntil(cmd, limit, stderr, nil)
+}
+
+// executeUntil is execute, but cmd is also killed once stop is closed
+func executeUntil(cmd *exec.Cmd, limit judgeLimit, stderr io.Writer, stop <-chan struct{}) (*execution, error) {
+	tail := &tailWriter{max: 4096}
+	cmd.Stderr = io.MultiWriter(stderr, tail)
+	setProcessGroup(cmd)
+	limiter := newMemoryLimiter(cmd, limit.Memory)
+	defer limiter.close()
+	if limit.Sandbox {
+		if err := sandbox(cmd, limiter.childRlimit()); err != nil {
+			return nil, err
+		}
+	}
+	limiter.wrap(cmd)
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("Runtime Error #%v ... %v", sampleID, err.Error())
+		return nil, err
+	}
+	limiter.started()
+	if limiter.sampling() {
+		limiter.sample(cmd.Process.Pid)
+	}
+
+	// The process is killed when the wall time exceeds twice the time limit,
+	// the verdict itself is decided by cpu time.
+	var timeout <-chan time.Time
+	if limit.Time > 0 {
+		timer := time.NewTimer(2 * limit.Time)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	// The memory is sampled while the process is running, unless the cgroup
+	// reports the peak. ru_maxrss is never used, since it includes the memory
+	// of cf, which the child holds until exec
+	var poll <-chan time.Time
+	if !limiter.exact() {
+		ticker := time.NewTicker(5 * time.Millisecond)
+		defer ticker.Stop()
+		poll = ticker.C
 	}
 
 	pid := int32(cmd.Process.Pid)
-	maxMemory := uint64(0)
-	ch := make(chan error)
+	e := &execution{}
+	ch := make(chan error, 1)
 	go func() {
-		ch <- cmd.Wait()
+		ch <- waitProcess(cmd)
 	}()
 	running := true
 	for running {
 		select {
-		case err := <-ch:
-			if err != nil {
-				return fmt.Errorf("Runtime Error #%v ... %v", sampleID, err.Error())
-			}
+		case e.Err = <-ch:
 			running = false
-		default:
+		case <-timeout:
+			killProcessTree(cmd)
+			e.Killed = true
+		case <-stop:
+			killProcessTree(cmd)
+			e.Stopped = true
+			stop = nil
+		case <-interrupt:
+			killProcessTree(cmd)
+			<-ch
+			return nil, errInterrupted
+		case <-poll:
+			if limiter.sampling() {
+				limiter.sample(cmd.Process.Pid)
+				continue
+			}
 			p, err := process.NewProcess(pid)
 			if err == nil {
 				m, err := p.MemoryInfo()
-				if err == nil && m.RSS > maxMemory {
-					maxMemory = m.RSS
+				if err == nil && m.RSS > e.Memory {
+					e.Memory = m.RSS
 				}
 			}
 		}
 	}
+	// The memory is unknown, i.e. 0, if a program exited before any sample
+	if m, exact := limiter.peak(); exact || m > 0 {
+		e.Memory = m
+	}
+	e.Violation = limit.Sandbox && securityViolation(cmd.ProcessState)
+	// The helper re-executing cf before the program is not counted
+	e.CPUTime = cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime() - limiter.helperTime()
+	e.Killed = e.Killed || (limit.Time > 0 && e.CPUTime > limit.Time)
+	e.OOM = limiter.oomKilled() || (limit.Memory > 0 && e.Memory > limit.Memory) ||
+		(e.Err != nil && limiter.enforced() && (allocFailureReg.Match(tail.buf) || limiter.nearLimit()))
+	return e, nil
+}
+
+// verdict Security Violation, Time Limit Exceeded or Memory Limit Exceeded,
+// empty if none of them
+func (e *execution) verdict() string {
+	if e.Violation {
+		return verdictSecurityViolation
+	}
+	if e.Killed {
+		return verdictTimeLimit
+	}
+	if e.OOM {
+		return verdictMemoryLimit
+	}
+	return ""
+}
+
+// judge a sample and print the report to w. The standard error of the
+// program is written to stderr
+func judge(sampleID string, conf judgeConfig, w, stderr io.Writer) error {
+	r, err := runSample(sampleID, conf, stderr)
+	if err != nil {
+		return err
+	}
+	r.print(w)
+	return nil
+}
+
+// runSample judges a sample. The standard error of the program is written to
+// stderr. An error is returned only if the judging is interrupted or the
+// files cannot be created. A sample which cannot be read fails
+func runSample(sampleID string, conf judgeConfig, stderr io.Writer) (*sampleResult, error) {
+	valid, message, err := validateFile(conf.Validator, inputPath(sampleID), conf.Limit)
+	if err != nil {
+		return &sampleResult{ID: sampleID, Verdict: verdictValidatorFailed, Message: err.Error()}, nil
+	}
+	if !valid {
+		return &sampleResult{ID: sampleID, Verdict: verdictInvalidInput, Message: message}, nil
+	}
+	if conf.Interactor != "" {
+		return runInteractive(sampleID, conf, stderr)
+	}
+	inPath := inputPath(sampleID)
+	ansPath := answerPath(sampleID)
+	input, err := os.Open(inPath)
+	if err != nil {
+		return &sampleResult{ID: sampleID, Verdict: verdictFailed, Message: err.Error()}, nil
+	}
+	defer input.Close()
+	var o bytes.Buffer
+	r := &sampleResult{ID: sampleID}
+
+	cmds := splitCmd(conf.Command)
+	cmd := exec.Command(cmds[0], cmds[1:]...)
+	cmd.Stdin = input
+	cmd.Stdout = &o
+	if conf.InputFile != "" || conf.OutputFile != "" {
+		dir, err := useFileIO(cmd, conf, inPath)
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+	}
+	e, err := execute(cmd, conf.Limit, stderr)
+	if err == errInterrupted {
+		return nil, err
+	}
+	if err != nil {
+		r.Verdict, r.Message = verdictRuntimeError, err.Error()
+		return r, nil
+	}
+	r.measure(e)
+	if r.Verdict = e.verdict(); r.Verdict != "" {
+		return r, nil
+	}
+	if e.Err != nil {
+		r.Verdict, r.Message = verdictRuntimeError, e.Err.Error()
+		return r, nil
+	}
 
-	b, err := os.ReadFile(ansPath)
+	output := o.Bytes()
+	if conf.OutputFile != "" {
+		if output, err = os.ReadFile(filepath.Join(cmd.Dir, conf.OutputFile)); err != nil {
+			r.Verdict, r.Message = verdictFailed, fmt.Sprintf("Cannot find the output file %v", conf.OutputFile)
+			return r, nil
+		}
+	}
+	ok, message, err := verify(conf, inPath, ansPath, output)
 	if err != nil {
-		b = []byte{}
+		r.Verdict, r.Message = verdictCheckerFailed, err.Error()
+		return r, nil
+	}
+	if ok {
+		r.Verdict = verdictPassed
+		return r, nil
 	}
-	ans := plain(b)
-	out := plain(o.Bytes())
 
-	state := ""
-	diff := ""
-	if out == ans {
-		state = color.New(color.FgGreen).Sprintf("Passed #%v", sampleID)
+	in, _ := os.ReadFile(inPath)
+	answer, _ := os.ReadFile(ansPath)
+	r.Verdict, r.Input, r.Message = verdictFailed, string(in), message
+	if conf.Checker != "" {
+		r.Output, r.Answer = string(output), string(answer)
+		r.checked = true
+	} else if conf.Epsilon > 0 {
+		// Lines may differ where the numbers are close enough
+		_, _, at := compareFloat(output, answer, conf.Epsilon)
+		r.setTokenDiff(plain(output), plain(answer), at)
+		r.setInnerTest(conf.Tests[sampleID], conf.AnswerTests[sampleID])
 	} else {
-		input, err := os.ReadFile(inPath)
+		r.setDiff(plain(output), plain(answer))
+		r.setInnerTest(conf.Tests[sampleID], conf.AnswerTests[sampleID])
+	}
+	return r, nil
+}
+
+// verify the output of a sample by the checker, by the epsilon, or exactly.
+// The message explains why the output is wrong
+func verify(conf judgeConfig, inPath, ansPath string, output []byte) (ok bool, message string, err error) {
+	if conf.Checker != "" {
+		return check(conf.Checker, inPath, ansPath, output, conf.Limit)
+	}
+	answer, err := os.ReadFile(ansPath)
+	if err != nil {
+		return false, fmt.Sprintf("Cannot read the answer %v", ansPath), nil
+	}
+	if conf.Epsilon > 0 {
+		ok, message, _ = compareFloat(output, answer, conf.Epsilon)
+		return ok, message, nil
+	}
+	return plain(output) == plain(answer), "", nil
+}
+
+// judgeAll samples with at most jobs samples running at the same time. The
+// reports are printed to w in the order of samples, or not printed if w is
+// nil
+func judgeAll(samples []string, conf judgeConfig, jobs int, w io.Writer) ([]*sampleResult, error) {
+	results := make([]*sampleResult, len(samples))
+	if jobs <= 1 {
+		for i, id := range samples {
+			r, err := runSample(id, conf, os.Stderr)
+			if err != nil {
+				return nil, err
+			}
+			if w != nil {
+				r.print(w)
+			}
+			results[i] = r
+		}
+		return results, nil
+	}
+
+	type report struct {
+		stderr bytes.Buffer
+		result *sampleResult
+		err    error
+		done   chan struct{}
+	}
+	reports := make([]report, len(samples))
+	for i := range reports {
+		reports[i].done = make(chan struct{})
+	}
+	queue := make(chan int)
+	quit := make(chan struct{})
+	var once sync.Once
+	stop := func() {
+		once.Do(func() { close(quit) })
+	}
+	// The workers are stopped and waited for before returning, so that no
+	// sample starts after an interruption and outlives cf
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer stop()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			stop()
+		case <-quit:
+		}
+	}()
+	go func() {
+		defer close(queue)
+		for i := range samples {
+			select {
+			case queue <- i:
+			case <-quit:
+				return
+			}
+		}
+	}()
+	for k := 0; k < jobs; k++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				r := &reports[i]
+				select {
+				case <-quit:
+					r.err = errInterrupted
+				default:
+					r.result, r.err = runSample(samples[i], conf, &r.stderr)
+				}
+				close(r.done)
+			}
+		}()
+	}
+	for i := range reports {
+		r := &reports[i]
+		<-r.done
+		if r.err != nil {
+			return nil, r.err
+		}
+		if w != nil {
+			w.Write(r.stderr.Bytes())
+			r.result.print(w)
+		} else {
+			os.Stderr.Write(r.stderr.Bytes())
+		}
+		results[i] = r.result
+	}
+	return results, nil
+}
+
+// getLimit of current problem. The arguments come first, then problem.json,
+// then the limits on the problem's page. Zero means there is no limit. A
+// limit given by the arguments is final, even if it's zero. The memory limit
+// of a template is applied by judgeConfig.withTemplate
+func getLimit() (limit judgeLimit, err error) {
+	limit.Sandbox = Args.Sandbox
+	fixedTime, fixedMemory := Args.TimeLimit != "", Args.MemoryLimit != ""
+	if fixedTime {
+		sec, err := strconv.ParseFloat(Args.TimeLimit, 64)
+		if err != nil || sec < 0 {
+			return limit, fmt.Errorf("Invalid time limit %v", Args.TimeLimit)
+		}
+		limit.Time = time.Duration(sec * float64(time.Second))
+	}
+	if fixedMemory {
+		mb, err := strconv.ParseUint(Args.MemoryLimit, 10, 64)
 		if err != nil {
-			return err
+			return limit, fmt.Errorf("Invalid memory limit %v", Args.MemoryLimit)
 		}
-		state = color.New(color.FgRed).Sprintf("Failed #%v", sampleID)
-		dmp := diffmatchpatch.New()
-		d := dmp.DiffMain(out, ans, true)
-		diff += color.New(color.FgCyan).Sprintf("-----Input-----\n")
-		diff += string(input) + "\n"
-		diff += color.New(color.FgCyan).Sprintf("-----Output-----\n")
-		diff += dmp.DiffText1(d) + "\n"
-		diff += color.New(color.FgCyan).Sprintf("-----Answer-----\n")
-		diff += dmp.DiffText2(d) + "\n"
-		diff += color.New(color.FgCyan).Sprintf("-----Diff-----\n")
-		diff += dmp.DiffPrettyText(d) + "\n"
+		limit.Memory = mb * 1024 * 1024
 	}
 
-	parseMemory := func(memory uint64) string {
-		if memory > 1024*1024 {
-			return fmt.Sprintf("%.3fMB", float64(memory)/1024.0/1024.0)
-		} else if memory > 1024 {
-			return fmt.Sprintf("%.3fKB", float64(memory)/1024.0)
+	problem, err := client.LoadProblem(".")
+	if err != nil {
+		return
+	}
+	if !fixedTime && problem.TimeLimit > 0 {
+		limit.Time = time.Duration(problem.TimeLimit * float64(time.Second))
+	}
+	if !fixedMemory && problem.MemoryLimit > 0 {
+		limit.Memory = problem.MemoryLimit * 1024 * 1024
+	}
+	// The limits are fetched from codeforces only if none is set locally,
+	// otherwise every run would wait for the network
+	if fixedTime || fixedMemory || limit.Time > 0 || limit.Memory > 0 {
+		return
+	}
+
+	info := Args.Info
+	if info.ContestID == "" || info.ProblemID == "" || info.ProblemType == "acmsguru" {
+		return
+	}
+	problems, e := client.Instance.Statis(info)
+	if e != nil {
+		color.Yellow("Cannot get the limits of problem %v: %v", info.ProblemID, e.Error())
+		return
+	}
+	for _, problem := range problems {
+		if !strings.EqualFold(problem.ID, info.ProblemID) {
+			continue
+		}
+		if limit.Time, e = problem.TimeLimit(); e != nil {
+			color.Yellow("Cannot get the time limit of problem %v: %v", info.ProblemID, e.Error())
+		}
+		if limit.Memory, e = problem.MemoryLimit(); e != nil {
+			color.Yellow("Cannot get the memory limit of problem %v: %v", info.ProblemID, e.Error())
 		}
-		return fmt.Sprintf("%vB", memory)
 	}
+	return
+}
+
+// getEpsilon for comparing real numbers. The argument comes first, then
+// problem.json in current folder. Zero means comparing outputs exactly, unless
+// the template sets it, see judgeConfig.withTemplate
+func getEpsilon() (float64, error) {
+	if Args.Epsilon != "" {
+		eps, err := strconv.ParseFloat(Args.Epsilon, 64)
+		if err != nil || eps < 0 {
+			return 0, fmt.Errorf("Invalid epsilon %v", Args.Epsilon)
+		}
+		return eps, nil
+	}
+	problem, err := client.LoadProblem(".")
+	if err != nil {
+		return 0, err
+	}
+	return problem.Epsilon, nil
+}
+
+// codeScripts runs the scripts of a template for a code file
+type codeScripts struct {
+	template               config.CodeTemplate
+	path, full, file, rand string
+	// output of the scripts
+	output io.Writer
+}
+
+func newCodeScripts(filename string, template config.CodeTemplate) *codeScripts {
+	path, full := filepath.Split(filename)
+	ext := filepath.Ext(filename)
+	return &codeScripts{
+		template: template,
+		path:     path,
+		full:     full,
+		file:     full[:len(full)-len(ext)],
+		rand:     util.RandString(8),
+		output:   os.Stdout,
+	}
+}
+
+// filter replaces the placeholders in cmd
+func (c *codeScripts) filter(cmd string) string {
+	cmd = strings.ReplaceAll(cmd, "$%rand%$", c.rand)
+	cmd = strings.ReplaceAll(cmd, "$%path%$", c.path)
+	cmd = strings.ReplaceAll(cmd, "$%full%$", c.full)
+	cmd = strings.ReplaceAll(cmd, "$%file%$", c.file)
+	return cmd
+}
 
-	ansi.Printf("%v ... %.3fs %v\n%v", state, cmd.ProcessState.UserTime().Seconds(), parseMemory(maxMemory), diff)
+func (c *codeScripts) run(script string) error {
+	if s := c.filter(script); len(s) > 0 {
+		fmt.Fprintln(c.output, s)
+		cmds := splitCmd(s)
+		cmd := exec.Command(cmds[0], cmds[1:]...)
+		cmd.Stdout = c.output
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	}
+	return nil
+}
+
+// before runs before_script. It's skipped if the code has been compiled by
+// the same scripts, and the outputs are taken from the cache
+func (c *codeScripts) before() error {
+	if c.filter(c.template.BeforeScript) == "" {
+		return nil
+	}
+	dir, err := c.cacheDir()
+	if err != nil {
+		return c.run(c.template.BeforeScript)
+	}
+	if c.restoreCache(dir) {
+		fmt.Fprintf(c.output, "%v (cached)\n", c.filter(c.template.BeforeScript))
+		return nil
+	}
+	state := c.folderState()
+	if err = c.run(c.template.BeforeScript); err != nil {
+		return err
+	}
+	if err = c.saveCache(dir, state); err != nil {
+		color.Yellow("Cannot cache the compilation: %v", err.Error())
+	}
 	return nil
 }
 
+// sources the expansions of "$%full%$" and "$%file%$" in script, with or
+// without "$%path%$"
+func (c *codeScripts) sources() (sources []string) {
+	if strings.Contains(c.template.Script, "$%full%$") {
+		sources = append(sources, c.full, c.path+c.full)
+	}
+	if strings.Contains(c.template.Script, "$%file%$") {
+		sources = append(sources, c.file, c.path+c.file)
+	}
+	return
+}
+
+// command of script which runs the code
+func (c *codeScripts) command() (string, error) {
+	if s := c.filter(c.template.Script); len(s) > 0 {
+		return s, nil
+	}
+	return "", errors.New("Invalid script command. Please check config file")
+}
+
+// after runs after_script
+func (c *codeScripts) after() error {
+	return c.run(c.template.AfterScript)
+}
+
+// newJudgeConfig how to judge the code of the template in current folder
+func newJudgeConfig(template config.CodeTemplate) (judgeConfig, error) {
+	conf, err := newProblemConfig()
+	if err != nil {
+		return conf, err
+	}
+	return conf.withTemplate(template), nil
+}
+
+// newProblemConfig how to judge any code in current folder. The settings of
+// templates are not applied yet
+func newProblemConfig() (conf judgeConfig, err error) {
+	if conf.Limit, err = getLimit(); err != nil {
+		return
+	}
+	if conf.Checker, err = findProgram("checker"); err != nil {
+		return
+	}
+	if conf.Epsilon, err = getEpsilon(); err != nil {
+		return
+	}
+	if conf.Interactor = Args.Interactor; conf.Interactor == "" {
+		if conf.Interactor, err = findProgram("interactor"); err != nil {
+			return
+		}
+	}
+	if conf.Validator = Args.Validator; conf.Validator == "" {
+		if conf.Validator, err = findProgram("validator"); err != nil {
+			return
+		}
+	}
+	problem, err := client.LoadProblem(".")
+	if err != nil {
+		return
+	}
+	if problem.Interactive && conf.Interactor == "" {
+		return conf, errors.New(`The problem is interactive. Create "interactor.<suffix>" or specify it by -i`)
+	}
+	// The full tests are not the samples
+	if !Args.Full {
+		conf.Tests, conf.AnswerTests = problem.SampleTests, problem.AnswerTests
+	}
+	conf.InputFile, conf.OutputFile = problem.InputFile, problem.OutputFile
+	return
+}
+
+// withTemplate applies the settings of the template unless the arguments or
+// current folder set them. A checker in current folder comes before the
+// checker of the template
+func (conf judgeConfig) withTemplate(template config.CodeTemplate) judgeConfig {
+	if Args.MemoryLimit == "" && template.MemoryLimit > 0 {
+		conf.Limit.Memory = uint64(template.MemoryLimit) * 1024 * 1024
+	}
+	if conf.Checker == "" {
+		conf.Checker = template.Checker
+	}
+	if Args.Epsilon == "" && conf.Epsilon == 0 {
+		conf.Epsilon = template.Epsilon
+	}
+	return conf
+}
+
+// ErrFailed is returned when some samples failed. Their reports have been
+// printed, so it needs no more message
+var ErrFailed = errors.New("Some samples failed")
+
+// ExitFailure whether cf exits with 1 after err: some samples failed, or cf
+// test with a report of json or junit failed without it. The scripts reading
+// the report tell the result by the exit code
+func ExitFailure(err error) bool {
+	if err == ErrFailed {
+		return true
+	}
+	return err != nil && Args != nil && Args.Test && (Args.Format == "json" || Args.Format == "junit")
+}
+
 // Test command
 func Test() (err error) {
+	if Args.Compare {
+		return TestCompare()
+	}
 	cfg := config.Instance
 	if len(cfg.Template) == 0 {
 		return errors.New("You have to add at least one code template by `cf config`")
 	}
-	samples := getSampleID()
-	if len(samples) == 0 {
-		return errors.New("Cannot find any sample file")
+	format := Args.Format
+	switch format {
+	case "", "text":
+		format = "text"
+	case "json", "junit":
+		// Keep the standard output clean for the report
+		color.Output = ansi.NewAnsiStderr()
+	default:
+		return fmt.Errorf("Invalid format %v", Args.Format)
 	}
-	filename, index, err := getOneCode(Args.File, cfg.Template)
+	filename, index, err := getOneSolution(Args.File, cfg.Template)
 	if err != nil {
 		return
 	}
-	template := cfg.Template[index]
-	path, full := filepath.Split(filename)
-	ext := filepath.Ext(filename)
-	file := full[:len(full)-len(ext)]
-	rand := util.RandString(8)
-
-	filter := func(cmd string) string {
-		cmd = strings.ReplaceAll(cmd, "$%rand%$", rand)
-		cmd = strings.ReplaceAll(cmd, "$%path%$", path)
-		cmd = strings.ReplaceAll(cmd, "$%full%$", full)
-		cmd = strings.ReplaceAll(cmd, "$%file%$", file)
-		return cmd
-	}
-
-	run := func(script string) error {
-		if s := filter(script); len(s) > 0 {
-			fmt.Println(s)
-			cmds := splitCmd(s)
-			cmd := exec.Command(cmds[0], cmds[1:]...)
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
-			return cmd.Run()
+	jobs := 1
+	if Args.Jobs != "" {
+		if jobs, err = strconv.Atoi(Args.Jobs); err != nil || jobs < 1 {
+			return fmt.Errorf("Invalid number of jobs %v", Args.Jobs)
 		}
-		return nil
 	}
-
-	if err = run(template.BeforeScript); err != nil {
+	template := cfg.Template[index]
+	conf, err := newJudgeConfig(template)
+	if err != nil {
 		return
 	}
-	if s := filter(template.Script); len(s) > 0 {
-		for _, i := range samples {
-			err := judge(i, s)
-			if err != nil {
-				color.Red(err.Error())
-			}
+	scripts := newCodeScripts(filename, template)
+	if format != "text" {
+		scripts.output = os.Stderr
+	}
+	test := func() (err error) {
+		samples := getSampleID()
+		if len(samples) == 0 {
+			return errors.New("Cannot find any sample file")
 		}
-	} else {
-		return errors.New("Invalid script command. Please check config file")
+		if missing := missingAnswers(); len(missing) > 0 {
+			color.Yellow("Skip inputs without answers: %v. Run `cf testcase gen-ans` to generate them",
+				strings.Join(missing, ", "))
+		}
+		if err = scripts.before(); err != nil {
+			return
+		}
+		defer cleanUp(scripts, &err)
+		if conf.Command, err = scripts.command(); err != nil {
+			return
+		}
+		conf.Sources = scripts.sources()
+		var w io.Writer
+		if format == "text" {
+			w = color.Output
+		}
+		results, err := judgeAll(samples, conf, jobs, w)
+		if err != nil {
+			return
+		}
+		if err := recordHistory(filename, results); err != nil {
+			color.Yellow("Cannot record the history: %v", err.Error())
+		}
+
+		report := newTestReport(filename, results)
+		switch format {
+		case "json":
+			err = report.writeJSON(os.Stdout)
+		case "junit":
+			err = report.writeJUnit(os.Stdout)
+		}
+		// The exit code tells the result to the scripts
+		if err == nil && report.Passed < report.Total {
+			err = ErrFailed
+		}
+		return
+	}
+	if Args.WatchTest {
+		return watchTest(filename, format, test)
 	}
-	return run(template.AfterScript)
+	return test()
 }
@@ -0,0 +1,323 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/NetWilliam/cf-tool/config"
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/renderer"
+	"github.com/olekukonko/tablewriter/tw"
+)
+
+// compareGenerated the number of generated inputs of "cf test --compare"
+const compareGenerated = 10
+
+// implementation a code with one of the templates matching it. Conf is the
+// judge config of the template. scripts and command are set once it's
+// prepared by prepareCode
+type implementation struct {
+	name     string
+	filename string
+	template config.CodeTemplate
+	scripts  *codeScripts
+	command  string
+	conf     judgeConfig
+}
+
+// compareInput an input which all implementations run on. answer is empty if
+// there is no answer
+type compareInput struct {
+	name   string
+	path   string
+	answer string
+}
+
+// shortVerdicts for the cells of the matrix
+var shortVerdicts = map[string]string{
+	verdictPassed:            "OK",
+	verdictFailed:            "WA",
+	verdictTimeLimit:         "TLE",
+	verdictMemoryLimit:       "MLE",
+	verdictRuntimeError:      "RE",
+	verdictSecurityViolation: "SV",
+	verdictCheckerFailed:     "CF",
+}
+
+// findImplementations finds every code in current folder with every template
+// matching it. They are not prepared yet. problem is the config of the
+// problem without templates
+func findImplementations(templates []config.CodeTemplate) (problem judgeConfig, impls []*implementation, err error) {
+	codes, err := getSolutionCode("", templates)
+	if err != nil {
+		return
+	}
+	if problem, err = newProblemConfig(); err != nil {
+		return
+	}
+	if problem.Interactor != "" {
+		return problem, nil, errors.New("cf test --compare doesn't support interactive problems")
+	}
+	if err = checkStandardIO("cf test --compare", problem.InputFile, problem.OutputFile); err != nil {
+		return
+	}
+	for _, code := range codes {
+		for _, index := range code.Index {
+			template := templates[index]
+			name := code.Name
+			if len(code.Index) > 1 {
+				name = fmt.Sprintf("%v (%v)", code.Name, template.Alias)
+			}
+			impls = append(impls, &implementation{
+				name:     name,
+				filename: code.Name,
+				template: template,
+				conf:     problem.withTemplate(template),
+			})
+		}
+	}
+	if len(impls) < 2 {
+		return problem, nil, errors.New("cf test --compare needs at least two codes in current folder")
+	}
+	return
+}
+
+// compareInputs are the samples and the inputs generated by the generator if
+// there is one. Generated inputs are saved in dir. The validator of problem
+// checks all of them if there is one: samples which it rejects are skipped,
+// and like in cf stress, a rejected generated input stops the comparison
+func compareInputs(templates []config.CodeTemplate, problem judgeConfig, dir string) (inputs []*compareInput, err error) {
+	for _, id := range getSampleID() {
+		valid, message, err := validateFile(problem.Validator, inputPath(id), problem.Limit)
+		if err != nil {
+			return nil, fmt.Errorf("Validator failed on sample %v: %v", id, err.Error())
+		}
+		if !valid {
+			color.Yellow("Skip sample %v. Invalid input: %v", id, message)
+			continue
+		}
+		inputs = append(inputs, &compareInput{"#" + id, inputPath(id), answerPath(id)})
+	}
+	genFile := Args.Generator
+	if genFile == "" {
+		genFile = findCodeByName("gen", templates)
+	}
+	if genFile == "" {
+		return
+	}
+	count := compareGenerated
+	if Args.Count != "" {
+		if count, err = strconv.Atoi(Args.Count); err != nil || count < 1 {
+			return nil, fmt.Errorf("Invalid count %v", Args.Count)
+		}
+	}
+	gen, command, err := prepareHelper(genFile, templates)
+	if err != nil {
+		return
+	}
+	defer cleanUp(gen, &err)
+	for i := 0; i < count; i++ {
+		seed := rand.Int63()
+		data, e, err := runHelper(command, []string{fmt.Sprint(seed)}, nil, judgeLimit{}, os.Stderr)
+		if err != nil {
+			return nil, err
+		}
+		if e.Err != nil {
+			return nil, fmt.Errorf("Generator failed with seed %v: %v", seed, e.Err.Error())
+		}
+		valid, message, err := validate(problem.Validator, data, problem.Limit)
+		if err != nil {
+			return nil, fmt.Errorf("Validator failed with seed %v: %v", seed, err.Error())
+		}
+		if !valid {
+			return nil, fmt.Errorf("%v with seed %v: %v", errInvalidInput.Error(), seed, message)
+		}
+		path := filepath.Join(dir, fmt.Sprintf("in%v.txt", i))
+		if err = os.WriteFile(path, data, 0644); err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, &compareInput{name: fmt.Sprintf("seed %v", seed), path: path})
+	}
+	return inputs, nil
+}
+
+// compareRun runs the implementation on the input and returns the cell of the
+// matrix and the output, which is nil if it didn't finish normally
+func compareRun(impl *implementation, input *compareInput) (string, []byte, error) {
+	conf := impl.conf
+	data, err := os.ReadFile(input.path)
+	if err != nil {
+		return "", nil, err
+	}
+	output, e, err := runCode(impl.command, nil, data, conf.Limit, io.Discard)
+	if err != nil {
+		return "", nil, err
+	}
+	verdict := e.verdict()
+	if verdict == "" && e.Err != nil {
+		verdict = verdictRuntimeError
+	}
+	if verdict != "" {
+		return shortVerdicts[verdict], nil, nil
+	}
+	cell := fmt.Sprintf("%.3fs", e.CPUTime.Seconds())
+	if input.answer == "" {
+		return cell, output, nil
+	}
+	ok, _, err := verify(conf, input.path, input.answer, output)
+	if err != nil {
+		return shortVerdicts[verdictCheckerFailed], output, nil
+	}
+	if ok {
+		return "OK " + cell, output, nil
+	}
+	return "WA " + cell, output, nil
+}
+
+// outputGroup implementations whose outputs on an input are the same as the
+// output saved in path
+type outputGroup struct {
+	path  string
+	names []string
+}
+
+// groupOutput finds the group of the output, or starts a new one. Outputs
+// are compared by the same rules as answers of the implementation
+func groupOutput(groups []*outputGroup, impl *implementation, input *compareInput, output []byte, dir string) ([]*outputGroup, int, error) {
+	for i, group := range groups {
+		if ok, _, _ := verify(impl.conf, input.path, group.path, output); ok {
+			group.names = append(group.names, impl.name)
+			return groups, i, nil
+		}
+	}
+	path := filepath.Join(dir, fmt.Sprintf("output%v.txt", len(groups)))
+	if err := os.WriteFile(path, output, 0644); err != nil {
+		return nil, 0, err
+	}
+	return append(groups, &outputGroup{path, []string{impl.name}}), len(groups), nil
+}
+
+// describeGroups e.g. "a.cpp, b.cpp vs c.py"
+func describeGroups(groups []*outputGroup) string {
+	sides := []string{}
+	for _, group := range groups {
+		sides = append(sides, strings.Join(group.names, ", "))
+	}
+	return strings.Join(sides, " vs ")
+}
+
+// TestCompare runs all codes in current folder on the same inputs and shows
+// where they disagree. Each code is judged by the config of its template.
+// When the outputs of a row differ, the cells are marked with the group of
+// the same outputs, e.g. [A] and [B], and the row with "*"
+func TestCompare() (err error) {
+	cfg := config.Instance
+	if len(cfg.Template) == 0 {
+		return errors.New("You have to add at least one code template by `cf config`")
+	}
+	problem, impls, err := findImplementations(cfg.Template)
+	if err != nil {
+		return
+	}
+	for _, impl := range impls {
+		impl.scripts, impl.command, err = prepareCode(impl.filename, impl.template)
+		if err != nil {
+			return fmt.Errorf("Cannot compile %v by %v: %v", impl.filename, impl.template.Alias, err.Error())
+		}
+		defer cleanUp(impl.scripts, &err)
+	}
+	dir, err := os.MkdirTemp("", "cf-compare-")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(dir)
+	inputs, err := compareInputs(cfg.Template, problem, dir)
+	if err != nil {
+		return
+	}
+	if len(inputs) == 0 {
+		return errors.New("Cannot find any sample file or generator")
+	}
+
+	table := tablewriter.NewTable(color.Output,
+		tablewriter.WithRenderer(renderer.NewBlueprint(tw.Rendition{
+			Symbols: tw.NewSymbols(tw.StyleASCII),
+		})),
+		tablewriter.WithHeaderAutoFormat(tw.Off),
+	)
+	header := []string{"INPUT"}
+	for _, impl := range impls {
+		header = append(header, impl.name)
+	}
+	table.Header(header)
+
+	outputDir := filepath.Join(dir, "outputs")
+	if err = os.Mkdir(outputDir, 0755); err != nil {
+		return
+	}
+	disagreed := []*compareInput{}
+	descriptions := map[*compareInput]string{}
+	for _, input := range inputs {
+		row := []string{input.name}
+		groups := []*outputGroup{}
+		cells := map[int]int{}
+		for _, impl := range impls {
+			cell, output, err := compareRun(impl, input)
+			if err != nil {
+				return err
+			}
+			row = append(row, cell)
+			if output == nil {
+				continue
+			}
+			var group int
+			if groups, group, err = groupOutput(groups, impl, input, output, outputDir); err != nil {
+				return err
+			}
+			cells[len(row)-1] = group
+		}
+		if len(groups) > 1 {
+			for i, group := range cells {
+				row[i] = fmt.Sprintf("[%c] %v", 'A'+group, row[i])
+			}
+			row[0] += " *"
+			disagreed = append(disagreed, input)
+			descriptions[input] = describeGroups(groups)
+		}
+		table.Append(row)
+	}
+	if err = table.Render(); err != nil {
+		return
+	}
+
+	if len(disagreed) == 0 {
+		color.Green("All codes agree on %v inputs", len(inputs))
+	}
+	for _, input := range disagreed {
+		if input.answer != "" {
+			color.Red("Outputs differ on sample %v: %v", input.name, descriptions[input])
+			continue
+		}
+		data, err := os.ReadFile(input.path)
+		if err != nil {
+			return err
+		}
+		sampleID := nextSampleID()
+		if err = dropSampleTests(sampleID); err != nil {
+			return err
+		}
+		if err = os.WriteFile(inputPath(sampleID), data, 0644); err != nil {
+			return err
+		}
+		color.Red("Outputs differ on the input of %v: %v. Saved as %v",
+			input.name, descriptions[input], inputPath(sampleID))
+	}
+	return
+}
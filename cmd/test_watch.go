@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+const (
+	// watchInterval how often the files are checked
+	watchInterval = 200 * time.Millisecond
+	// watchDebounce how long the files must stay unchanged before testing,
+	// since editors may write a file several times on saving
+	watchDebounce = 300 * time.Millisecond
+)
+
+// fileState what is compared to find out changes of a file
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// watchedFiles the states of the code and all tests in testDir
+func watchedFiles(filename string) map[string]fileState {
+	files := map[string]fileState{}
+	names := []string{filename}
+	if paths, err := os.ReadDir(testDir()); err == nil {
+		reg := regexp.MustCompile(`^(in|ans)\d+.txt$`)
+		for _, path := range paths {
+			if reg.MatchString(path.Name()) {
+				names = append(names, filepath.Join(testDir(), path.Name()))
+			}
+		}
+	}
+	for _, name := range names {
+		if info, err := os.Stat(name); err == nil {
+			files[name] = fileState{info.ModTime(), info.Size()}
+		}
+	}
+	return files
+}
+
+func sameFiles(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, state := range a {
+		if b[name] != state {
+			return false
+		}
+	}
+	return true
+}
+
+// waitChange blocks until the files are changed and then stay unchanged for
+// watchDebounce. It returns the new states
+func waitChange(filename string, last map[string]fileState) map[string]fileState {
+	for {
+		time.Sleep(watchInterval)
+		files := watchedFiles(filename)
+		if sameFiles(files, last) {
+			continue
+		}
+		for {
+			time.Sleep(watchDebounce)
+			next := watchedFiles(filename)
+			if sameFiles(files, next) {
+				return next
+			}
+			files = next
+		}
+	}
+}
+
+// watchTest runs test whenever the code or the samples are changed. The
+// previous report is cleared when format is "text"
+func watchTest(filename, format string, test func() error) error {
+	files := watchedFiles(filename)
+	for {
+		if format == "text" {
+			fmt.Fprint(color.Output, "\033[H\033[2J")
+		}
+		err := test()
+		if err == errInterrupted {
+			return err
+		}
+		if err != nil && err != ErrFailed {
+			color.Red(err.Error())
+		}
+		color.Cyan("Watching %v and samples. Press Ctrl+C to exit", filename)
+		files = waitChange(filename, files)
+	}
+}
@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+
+	"github.com/NetWilliam/cf-tool/client"
+	"github.com/NetWilliam/cf-tool/config"
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/renderer"
+	"github.com/olekukonko/tablewriter/tw"
+)
+
+// getInputID all "inK.txt" in testDir sorted by K, including the ones
+// without answers
+func getInputID() (inputs []string) {
+	paths, err := os.ReadDir(testDir())
+	if err != nil {
+		return
+	}
+	reg := regexp.MustCompile(`^in(\d+).txt$`)
+	for _, path := range paths {
+		if tmp := reg.FindStringSubmatch(path.Name()); tmp != nil {
+			inputs = append(inputs, tmp[1])
+		}
+	}
+	sort.Slice(inputs, func(i, j int) bool {
+		a, _ := strconv.Atoi(inputs[i])
+		b, _ := strconv.Atoi(inputs[j])
+		return a < b
+	})
+	return
+}
+
+// missingAnswers the inputs which have no answers
+func missingAnswers() (missing []string) {
+	for _, id := range getInputID() {
+		if _, err := os.Stat(answerPath(id)); err != nil {
+			missing = append(missing, id)
+		}
+	}
+	return
+}
+
+// openEditor opens the files with $VISUAL or $EDITOR and waits for it
+func openEditor(files ...string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+	cmds := splitCmd(editor)
+	cmd := exec.Command(cmds[0], append(cmds[1:], files...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// Testcase command
+func Testcase() (err error) {
+	if Args.Add {
+		return testcaseAdd()
+	} else if Args.List {
+		return testcaseList()
+	} else if Args.Rm {
+		return testcaseRm()
+	} else if Args.Edit {
+		return testcaseEdit()
+	} else if Args.GenAns {
+		return testcaseGenAns()
+	}
+	return nil
+}
+
+// testcaseAdd reads the input from stdin if it's not a terminal, otherwise
+// opens the input and the answer in the editor
+func testcaseAdd() error {
+	if err := os.MkdirAll(testDir(), 0755); err != nil {
+		return err
+	}
+	sampleID := nextSampleID()
+	if err := dropSampleTests(sampleID); err != nil {
+		return err
+	}
+	inPath := inputPath(sampleID)
+	ansPath := answerPath(sampleID)
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice == 0 {
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(input)) == 0 {
+			return errors.New("The input is empty, so the test is not added")
+		}
+		if err = os.WriteFile(inPath, input, 0644); err != nil {
+			return err
+		}
+		color.Green("Added %v. Run `cf testcase gen-ans` to generate its answer", inPath)
+		return nil
+	}
+
+	for _, path := range []string{inPath, ansPath} {
+		if err := os.WriteFile(path, []byte{}, 0644); err != nil {
+			return err
+		}
+	}
+	if err := openEditor(inPath, ansPath); err != nil {
+		return err
+	}
+	if info, err := os.Stat(inPath); err != nil || info.Size() == 0 {
+		os.Remove(inPath)
+		os.Remove(ansPath)
+		return errors.New("The input is empty, so the test is not added")
+	}
+	if info, err := os.Stat(ansPath); err == nil && info.Size() == 0 {
+		os.Remove(ansPath)
+		color.Green("Added %v. Run `cf testcase gen-ans` to generate its answer", inPath)
+		return nil
+	}
+	color.Green("Added %v and %v", inPath, ansPath)
+	return nil
+}
+
+func testcaseList() error {
+	inputs := getInputID()
+	if len(inputs) == 0 {
+		return errors.New("Cannot find any test")
+	}
+	table := tablewriter.NewTable(color.Output,
+		tablewriter.WithRenderer(renderer.NewBlueprint(tw.Rendition{
+			Symbols: tw.NewSymbols(tw.StyleASCII),
+		})),
+	)
+	table.Header("#", "INPUT", "ANSWER")
+	for _, id := range inputs {
+		input := inputPath(id)
+		if info, err := os.Stat(input); err == nil {
+			input = fmt.Sprintf("%v (%vB)", input, info.Size())
+		}
+		answer := answerPath(id)
+		if info, err := os.Stat(answer); err == nil {
+			answer = fmt.Sprintf("%v (%vB)", answer, info.Size())
+		} else {
+			answer = color.New(color.FgRed).Sprint("missing")
+		}
+		table.Append(id, input, answer)
+	}
+	return table.Render()
+}
+
+// checkTestID the tests of the IDs exist
+func checkTestID(ids []string) error {
+	for _, id := range ids {
+		if _, err := os.Stat(inputPath(id)); err != nil {
+			return fmt.Errorf("Cannot find test %v", id)
+		}
+	}
+	return nil
+}
+
+func testcaseRm() error {
+	if err := checkTestID(Args.TestID); err != nil {
+		return err
+	}
+	if err := dropSampleTests(Args.TestID...); err != nil {
+		return err
+	}
+	for _, id := range Args.TestID {
+		if err := os.Remove(inputPath(id)); err != nil {
+			return err
+		}
+		os.Remove(answerPath(id))
+		color.Green("Removed test %v", id)
+	}
+	return nil
+}
+
+func testcaseEdit() error {
+	if err := checkTestID(Args.TestID); err != nil {
+		return err
+	}
+	if err := dropSampleTests(Args.TestID...); err != nil {
+		return err
+	}
+	files := []string{}
+	for _, id := range Args.TestID {
+		files = append(files, inputPath(id), answerPath(id))
+	}
+	return openEditor(files...)
+}
+
+// testcaseGenAns runs the trusted solution on the inputs without answers, or
+// on the given tests
+func testcaseGenAns() (err error) {
+	cfg := config.Instance
+	if len(cfg.Template) == 0 {
+		return errors.New("You have to add at least one code template by `cf config`")
+	}
+	ids := Args.TestID
+	if len(ids) == 0 {
+		if ids = missingAnswers(); len(ids) == 0 {
+			color.Green("All inputs have answers")
+			return nil
+		}
+	} else if err = checkTestID(ids); err != nil {
+		return
+	}
+	problem, err := client.LoadProblem(".")
+	if err != nil {
+		return
+	}
+	if err = checkStandardIO("cf testcase gen-ans", problem.InputFile, problem.OutputFile); err != nil {
+		return
+	}
+	bruteFile := Args.Brute
+	if bruteFile == "" {
+		if bruteFile = findCodeByName("brute", cfg.Template); bruteFile == "" {
+			return errors.New(`Cannot find the trusted solution. Create "brute.<suffix>" or specify it by -b`)
+		}
+	}
+	brute, command, err := prepareHelper(bruteFile, cfg.Template)
+	if err != nil {
+		return
+	}
+	defer cleanUp(brute, &err)
+	for _, id := range ids {
+		input, err := os.ReadFile(inputPath(id))
+		if err != nil {
+			return err
+		}
+		answer, e, err := runHelper(command, nil, input, judgeLimit{}, os.Stderr)
+		if err != nil {
+			return err
+		}
+		if e.Err != nil {
+			return fmt.Errorf("Trusted solution failed on test %v: %v", id, e.Err.Error())
+		}
+		if err = os.WriteFile(answerPath(id), answer, 0644); err != nil {
+			return err
+		}
+		color.Green("Generated %v", answerPath(id))
+	}
+	return
+}
@@ -19,6 +19,9 @@ type CodeTemplate struct {
 	BeforeScript string   `json:"before_script"`
 	Script       string   `json:"script"`
 	AfterScript  string   `json:"after_script"`
+	MemoryLimit  int      `json:"memory_limit,omitempty"`
+	Checker      string   `json:"checker,omitempty"`
+	Epsilon      float64  `json:"epsilon,omitempty"`
 }
 
 // Config load and save configuration
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/NetWilliam/cf-tool/client"
@@ -121,9 +122,29 @@ func (c *Config) AddTemplate() (err error) {
 	color.Cyan(`After script (e.g. "rm $%file%$.exe" or "cmd.exe /C del $%file%$.exe" in windows), empty is ok: `)
 	afterScript := util.ScanlineTrim()
 
+	color.Cyan(`Memory limit in MB when testing (e.g. "512" for languages using a lot of memory), empty is ok: `)
+	memoryLimit := 0
+	for {
+		value := util.ScanlineTrim()
+		if value == "" {
+			break
+		}
+		if memoryLimit, err = strconv.Atoi(value); err == nil && memoryLimit >= 0 {
+			break
+		}
+		memoryLimit = 0
+		color.Red("Invalid memory limit. Please input again: ")
+	}
+
 	c.Template = append(c.Template, CodeTemplate{
-		alias, lang, path, suffix,
-		beforeScript, script, afterScript,
+		Alias:        alias,
+		Lang:         lang,
+		Path:         path,
+		Suffix:       suffix,
+		BeforeScript: beforeScript,
+		Script:       script,
+		AfterScript:  afterScript,
+		MemoryLimit:  memoryLimit,
 	})
 
 	if util.YesOrNo("Make it default (y/n)? ") {